@@ -19,4 +19,4 @@ fi
 
 echo "Running with: ${FLAGS}"
 
-torrserver $FLAGS
+exec torrserver $FLAGS
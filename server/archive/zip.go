@@ -0,0 +1,264 @@
+package archive
+
+import (
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Compression methods of zip entries that can be streamed
+const (
+	Store   = 0
+	Deflate = 8
+)
+
+const (
+	eocdSign       = 0x06054b50
+	eocd64Sign     = 0x06064b50
+	eocd64LocSign  = 0x07064b50
+	dirHeaderSign  = 0x02014b50
+	fileHeaderSign = 0x04034b50
+	// end of central directory with max comment
+	maxEOCD = 22 + 65535
+	// limits central directory read into memory
+	maxDir = 32 << 20
+)
+
+var ErrFormat = errors.New("not a zip archive")
+
+// Entry is file in zip archive
+type Entry struct {
+	Name           string `json:"name"`
+	Method         uint16 `json:"method"`
+	HeaderOffset   int64  `json:"offset"`
+	CompressedSize int64  `json:"csize"`
+	Size           int64  `json:"size"`
+}
+
+// IsZip checks file extension
+func IsZip(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".zip")
+}
+
+// ReadZipDir reads entries of zip from central directory at the end of it,
+// directories, encrypted entries and unsupported methods are skipped
+func ReadZipDir(r io.ReaderAt, size int64) ([]*Entry, error) {
+	tailSize := int64(maxEOCD)
+	if tailSize > size {
+		tailSize = size
+	}
+	tail := make([]byte, tailSize)
+	if _, err := r.ReadAt(tail, size-tailSize); err != nil && err != io.EOF {
+		return nil, err
+	}
+	pos := -1
+	for i := len(tail) - 22; i >= 0; i-- {
+		if binary.LittleEndian.Uint32(tail[i:]) == eocdSign {
+			pos = i
+			break
+		}
+	}
+	if pos < 0 {
+		return nil, ErrFormat
+	}
+	eocd := tail[pos:]
+	count := int64(binary.LittleEndian.Uint16(eocd[10:]))
+	dirSize := int64(binary.LittleEndian.Uint32(eocd[12:]))
+	dirOffset := int64(binary.LittleEndian.Uint32(eocd[16:]))
+	if count == 0xFFFF || dirSize == 0xFFFFFFFF || dirOffset == 0xFFFFFFFF {
+		// zip64 locator is just before end of central directory
+		loc := pos - 20
+		if loc < 0 || binary.LittleEndian.Uint32(tail[loc:]) != eocd64LocSign {
+			return nil, ErrFormat
+		}
+		off := int64(binary.LittleEndian.Uint64(tail[loc+8:]))
+		rec := make([]byte, 56)
+		if _, err := r.ReadAt(rec, off); err != nil {
+			return nil, err
+		}
+		if binary.LittleEndian.Uint32(rec) != eocd64Sign {
+			return nil, ErrFormat
+		}
+		count = int64(binary.LittleEndian.Uint64(rec[32:]))
+		dirSize = int64(binary.LittleEndian.Uint64(rec[40:]))
+		dirOffset = int64(binary.LittleEndian.Uint64(rec[48:]))
+	}
+	// zip64 values may be negative as int64
+	if dirSize < 0 || dirOffset < 0 || dirSize > maxDir || dirOffset+dirSize > size {
+		return nil, ErrFormat
+	}
+	dir := make([]byte, dirSize)
+	if _, err := r.ReadAt(dir, dirOffset); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for i := int64(0); i < count && len(dir) >= 46; i++ {
+		if binary.LittleEndian.Uint32(dir) != dirHeaderSign {
+			return nil, ErrFormat
+		}
+		flags := binary.LittleEndian.Uint16(dir[8:])
+		method := binary.LittleEndian.Uint16(dir[10:])
+		csize := int64(binary.LittleEndian.Uint32(dir[20:]))
+		usize := int64(binary.LittleEndian.Uint32(dir[24:]))
+		nameLen := int(binary.LittleEndian.Uint16(dir[28:]))
+		extraLen := int(binary.LittleEndian.Uint16(dir[30:]))
+		commentLen := int(binary.LittleEndian.Uint16(dir[32:]))
+		offset := int64(binary.LittleEndian.Uint32(dir[42:]))
+		end := 46 + nameLen + extraLen + commentLen
+		if end > len(dir) {
+			return nil, ErrFormat
+		}
+		rawName := dir[46 : 46+nameLen]
+		extra := dir[46+nameLen : 46+nameLen+extraLen]
+		dir = dir[end:]
+
+		name := ""
+		for len(extra) >= 4 {
+			tag := binary.LittleEndian.Uint16(extra)
+			n := int(binary.LittleEndian.Uint16(extra[2:]))
+			if 4+n > len(extra) {
+				break
+			}
+			field := extra[4 : 4+n]
+			switch tag {
+			case 0x0001:
+				// zip64 values are present only for overflowed fields
+				for _, v := range []*int64{&usize, &csize, &offset} {
+					if *v == 0xFFFFFFFF && len(field) >= 8 {
+						*v = int64(binary.LittleEndian.Uint64(field))
+						field = field[8:]
+					}
+				}
+			case 0x7075:
+				// info-zip unicode path: version, crc of raw name, name
+				if len(field) > 5 && field[0] == 1 {
+					name = string(field[5:])
+				}
+			}
+			extra = extra[4+n:]
+		}
+		if name == "" {
+			name = decodeName(rawName, flags&0x800 != 0)
+		}
+		name = strings.TrimLeft(strings.ReplaceAll(name, "\\", "/"), "/")
+		if name == "" || strings.HasSuffix(name, "/") || flags&1 != 0 || (method != Store && method != Deflate) {
+			continue
+		}
+		entries = append(entries, &Entry{
+			Name:           name,
+			Method:         method,
+			HeaderOffset:   offset,
+			CompressedSize: csize,
+			Size:           usize,
+		})
+	}
+	return entries, nil
+}
+
+// decodeName of entry, names without utf-8 flag are in dos code page, it is
+// mostly cp866 for cyrillic names
+func decodeName(raw []byte, isUTF8 bool) string {
+	if isUTF8 || utf8.Valid(raw) {
+		return string(raw)
+	}
+	name, err := charmap.CodePage866.NewDecoder().Bytes(raw)
+	if err != nil {
+		return string(raw)
+	}
+	return string(name)
+}
+
+// OpenEntry returns reader of entry data, stored entries support seeking,
+// deflated ones are decompressed from start on seek back
+func OpenEntry(r io.ReaderAt, e *Entry) (io.ReadSeeker, error) {
+	hdr := make([]byte, 30)
+	if _, err := r.ReadAt(hdr, e.HeaderOffset); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(hdr) != fileHeaderSign {
+		return nil, ErrFormat
+	}
+	dataOffset := e.HeaderOffset + 30 + int64(binary.LittleEndian.Uint16(hdr[26:])) + int64(binary.LittleEndian.Uint16(hdr[28:]))
+	if e.Method == Store {
+		return io.NewSectionReader(r, dataOffset, e.Size), nil
+	}
+	return &flateSeeker{data: io.NewSectionReader(r, dataOffset, e.CompressedSize), size: e.Size}, nil
+}
+
+// flateSeeker seeks lazily, data is skipped on next read
+type flateSeeker struct {
+	data *io.SectionReader
+	size int64
+	rc   io.ReadCloser
+	pos  int64
+	want int64
+}
+
+func (f *flateSeeker) Read(p []byte) (int, error) {
+	if f.want >= f.size {
+		return 0, io.EOF
+	}
+	if f.rc == nil || f.want < f.pos {
+		f.reset()
+	}
+	if f.want > f.pos {
+		n, err := io.CopyN(io.Discard, f.rc, f.want-f.pos)
+		f.pos += n
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.rc.Read(p)
+	f.pos += int64(n)
+	f.want = f.pos
+	return n, err
+}
+
+func (f *flateSeeker) reset() {
+	if f.rc != nil {
+		f.rc.Close()
+	}
+	f.data.Seek(0, io.SeekStart)
+	f.rc = flate.NewReader(f.data)
+	f.pos = 0
+}
+
+func (f *flateSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.want
+	case io.SeekEnd:
+		offset += f.size
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	f.want = offset
+	return offset, nil
+}
+
+// ReaderAt reads at offsets of seeker, it is used by one stream at a time
+type ReaderAt struct {
+	rs io.ReadSeeker
+	mu sync.Mutex
+}
+
+func NewReaderAt(rs io.ReadSeeker) *ReaderAt {
+	return &ReaderAt{rs: rs}
+}
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r.rs, p)
+}
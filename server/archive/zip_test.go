@@ -0,0 +1,95 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func testZip(t testing.TB) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	files := []struct {
+		name   string
+		method uint16
+		data   []byte
+	}{
+		{"dir/", zip.Store, nil},
+		{"dir/stored.txt", zip.Store, []byte("stored data")},
+		{"deflated.txt", zip.Deflate, bytes.Repeat([]byte("0123456789"), 10000)},
+	}
+	for _, f := range files {
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: f.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(f.data)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadZip(t *testing.T) {
+	data := testZip(t)
+	r := bytes.NewReader(data)
+	entries, err := ReadZipDir(r, int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name != "dir/stored.txt" || entries[1].Name != "deflated.txt" {
+		t.Fatalf("wrong entries %+v", entries)
+	}
+
+	rs, err := OpenEntry(r, entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf, _ := io.ReadAll(rs); string(buf) != "stored data" {
+		t.Fatalf("wrong stored data %q", buf)
+	}
+
+	rs, err = OpenEntry(r, entries[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// seek forward and back in deflated entry
+	for _, off := range []int64{50005, 12, 99990} {
+		if _, err = rs.Seek(off, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 5)
+		if _, err = io.ReadFull(rs, buf); err != nil {
+			t.Fatal(err)
+		}
+		want := bytes.Repeat([]byte("0123456789"), 2)[off%10 : off%10+5]
+		if !bytes.Equal(buf, want) {
+			t.Fatalf("at %d got %q, want %q", off, buf, want)
+		}
+	}
+}
+
+func TestReadZipBroken(t *testing.T) {
+	data := testZip(t)
+	for n := 0; n < len(data); n += 7 {
+		ReadZipDir(bytes.NewReader(data[:n]), int64(n))
+	}
+	if _, err := ReadZipDir(bytes.NewReader([]byte("not zip")), 7); err != ErrFormat {
+		t.Fatalf("got %v, want ErrFormat", err)
+	}
+}
+
+func FuzzReadZipDir(f *testing.F) {
+	f.Add(testZip(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := bytes.NewReader(data)
+		entries, _ := ReadZipDir(r, int64(len(data)))
+		for _, e := range entries {
+			if rs, err := OpenEntry(r, e); err == nil {
+				io.Copy(io.Discard, io.LimitReader(rs, 1<<20))
+			}
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"server/log"
+	"server/settings"
+)
+
+func backupRestore() int {
+	settings.InitSets(params.RDB)
+	defer settings.CloseDB()
+
+	if params.Backup != "" {
+		err := settings.WriteBackupFile(params.Backup)
+		if err != nil {
+			log.TLogln("Error write backup:", err)
+			return 1
+		}
+		log.TLogln("Backup saved to", params.Backup)
+	}
+
+	if params.Restore != "" {
+		rep, err := settings.RestoreBackupFile(params.Restore, params.RestoreMode, params.DryRun)
+		if err != nil {
+			log.TLogln("Error restore backup:", err)
+			return 1
+		}
+		buf, _ := json.MarshalIndent(rep, "", " ")
+		fmt.Println(string(buf))
+	}
+	return 0
+}
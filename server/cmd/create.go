@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"server/log"
+	"server/settings"
+	"server/torr"
+)
+
+func createTorrent() int {
+	settings.InitSets(params.RDB)
+	defer settings.CloseDB()
+
+	mi, info, err := torr.CreateTorrentFile(params.Create, params.PieceLength*1024, params.Trackers, "", "")
+	if err != nil {
+		log.TLogln("Error create torrent:", err)
+		return 1
+	}
+	hash := mi.HashInfoBytes()
+	fmt.Println(mi.Magnet(&hash, info).String())
+	return 0
+}
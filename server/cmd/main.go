@@ -3,12 +3,11 @@ package main
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"runtime"
-	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alexflint/go-arg"
@@ -19,22 +18,39 @@ import (
 	"server/settings"
 	"server/torr"
 	"server/version"
-	"server/web/api/utils"
+	"server/watch"
 )
 
 type args struct {
-	Port        string `arg:"-p" help:"web server port, default 8090"`
-	Path        string `arg:"-d" help:"database dir path"`
-	LogPath     string `arg:"-l" help:"server log file path"`
-	WebLogPath  string `arg:"-w" help:"web access log file path"`
-	RDB         bool   `arg:"-r" help:"start in read-only DB mode"`
-	HttpAuth    bool   `arg:"-a" help:"enable http auth on all requests"`
-	DontKill    bool   `arg:"-k" help:"don't kill server on signal"`
-	UI          bool   `arg:"-u" help:"open torrserver page in browser"`
-	TorrentsDir string `arg:"-t" help:"autoload torrents from dir"`
-	TorrentAddr string `help:"Torrent client address, default :32000"`
-	PubIPv4     string `arg:"-4" help:"set public IPv4 addr"`
-	PubIPv6     string `arg:"-6" help:"set public IPv6 addr"`
+	Port              string   `arg:"-p" help:"web server port, default 8090"`
+	Path              string   `arg:"-d" help:"database dir path"`
+	LogPath           string   `arg:"-l" help:"server log file path"`
+	WebLogPath        string   `arg:"-w" help:"web access log file path"`
+	LogSize           int64    `default:"100" help:"rotate logs over size in MB, 0 - don't rotate by size"`
+	LogFiles          int      `default:"2" help:"rotated log files to keep, 0 - all"`
+	LogAge            int      `help:"remove rotated logs older than days, 0 - keep"`
+	LogGzip           bool     `help:"compress rotated logs"`
+	RDB               bool     `arg:"-r" help:"start in read-only DB mode"`
+	HttpAuth          bool     `arg:"-a" help:"enable http auth on all requests"`
+	Admins            []string `help:"http auth users who see torrents and viewed of all users"`
+	UserLibs          bool     `help:"keep own torrents list for each http auth user"`
+	DontKill          bool     `arg:"-k" help:"don't kill server on signal"`
+	StopTimeout       int      `default:"10" help:"seconds to wait for active streams on shutdown"`
+	UI                bool     `arg:"-u" help:"open torrserver page in browser"`
+	TorrentsDir       string   `arg:"-t" help:"autoload torrents from dir"`
+	TorrentsDirPolicy string   `help:"what to do with loaded files from torrents dir: delete, keep or move, default delete"`
+	TorrentAddr       string   `help:"Torrent client address, default :32000"`
+	WebBind           string   `help:"web server bind address or interface name, default all"`
+	PubIPv4           string   `arg:"-4" help:"set public IPv4 addr"`
+	PubIPv6           string   `arg:"-6" help:"set public IPv6 addr"`
+	Backup            string   `help:"write library backup archive to file and exit"`
+	Restore           string   `help:"restore library from backup archive and exit"`
+	RestoreMode       string   `help:"restore mode: merge or replace, default merge"`
+	DryRun            bool     `help:"only report what restore would change"`
+	Create            string   `help:"create torrent from file or dir, save it for seeding and exit"`
+	ContentRoot       string   `help:"dir with content for torrents created by api, api create is off without it"`
+	PieceLength       int64    `help:"piece length in KB for created torrent, default auto"`
+	Trackers          []string `help:"trackers for created torrent"`
 }
 
 func (args) Version() string {
@@ -58,6 +74,15 @@ func main() {
 
 	settings.Path = params.Path
 	settings.HttpAuth = params.HttpAuth
+	settings.Admins = params.Admins
+	settings.ContentRoot = params.ContentRoot
+	settings.UserLibraries = params.UserLibs
+	log.SetRotate(log.RotateOptions{
+		MaxSize:  params.LogSize * 1024 * 1024,
+		MaxFiles: params.LogFiles,
+		MaxAge:   time.Duration(params.LogAge) * 24 * time.Hour,
+		Compress: params.LogGzip,
+	})
 	log.Init(params.LogPath, params.WebLogPath)
 	fmt.Println("=========== START ===========")
 	fmt.Println("TorrServer", version.Version+",", runtime.Version()+",", "CPU Num:", runtime.NumCPU())
@@ -65,8 +90,23 @@ func main() {
 		log.TLogln("Use HTTP Auth file", settings.Path+"/accs.db")
 	}
 
+	if params.Create != "" {
+		code := createTorrent()
+		log.Close()
+		os.Exit(code)
+	}
+
+	if params.Backup != "" || params.Restore != "" {
+		code := backupRestore()
+		log.Close()
+		os.Exit(code)
+	}
+
 	dnsResolve()
 	Preconfig(params.DontKill)
+	if !params.DontKill {
+		stopOnSignal()
+	}
 
 	if params.UI {
 		go func() {
@@ -79,6 +119,10 @@ func main() {
 		settings.TorAddr = params.TorrentAddr
 	}
 
+	if params.WebBind != "" {
+		settings.WebBind = params.WebBind
+	}
+
 	if params.PubIPv4 != "" {
 		settings.PubIPv4 = params.PubIPv4
 	}
@@ -88,14 +132,37 @@ func main() {
 	}
 
 	if params.TorrentsDir != "" {
-		go watchTDir(params.TorrentsDir)
+		go watch.Start(params.TorrentsDir, params.TorrentsDirPolicy)
 	}
 
 	server.Start(params.Port, params.RDB)
-	log.TLogln(server.WaitServer())
+	code := 0
+	if msg := server.WaitServer(); msg != "" {
+		log.TLogln(msg)
+		code = 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(params.StopTimeout)*time.Second)
+	if server.Stop(ctx) != 0 {
+		code = 1
+	}
+	cancel()
 	log.Close()
-	time.Sleep(time.Second * 3)
-	os.Exit(0)
+	os.Exit(code)
+}
+
+// stopOnSignal starts graceful shutdown on SIGTERM or SIGINT, second signal
+// quits immediately
+func stopOnSignal() {
+	sigc := make(chan os.Signal, 2)
+	signal.Notify(sigc, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		s := <-sigc
+		log.TLogln("Signal catched:", s)
+		torr.Shutdown()
+		<-sigc
+		log.TLogln("Second signal, quit")
+		os.Exit(1)
+	}()
 }
 
 func dnsResolve() {
@@ -118,37 +185,3 @@ func dnsResolve() {
 		log.TLogln("Check dns OK", addrs, err)
 	}
 }
-
-func watchTDir(dir string) {
-	time.Sleep(5 * time.Second)
-	path, err := filepath.Abs(dir)
-	if err != nil {
-		path = dir
-	}
-	for {
-		files, err := ioutil.ReadDir(path)
-		if err == nil {
-			for _, file := range files {
-				filename := filepath.Join(path, file.Name())
-				if strings.ToLower(filepath.Ext(file.Name())) == ".torrent" {
-					sp, err := utils.ParseLink("file://" + filename)
-					if err == nil {
-						tor, err := torr.AddTorrent(sp, "", "", "")
-						if err == nil {
-							if tor.GotInfo() {
-								if tor.Title == "" {
-									tor.Title = tor.Name()
-								}
-								torr.SaveTorrentToDB(tor)
-								tor.Drop()
-								os.Remove(filename)
-								time.Sleep(time.Second)
-							}
-						}
-					}
-				}
-			}
-		}
-		time.Sleep(time.Second)
-	}
-}
@@ -13,10 +13,20 @@ import (
 )
 
 func Preconfig(dkill bool) {
+	// rotate logs on SIGHUP
+	hupc := make(chan os.Signal, 1)
+	signal.Notify(hupc, syscall.SIGHUP)
+	go func() {
+		for range hupc {
+			if err := log.Rotate(); err != nil {
+				log.TLogln("Error rotate logs:", err)
+			}
+		}
+	}()
+
 	if dkill {
 		sigc := make(chan os.Signal, 1)
 		signal.Notify(sigc,
-			syscall.SIGHUP,
 			syscall.SIGINT,
 			syscall.SIGPIPE,
 			syscall.SIGTERM,
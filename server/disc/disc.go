@@ -0,0 +1,149 @@
+package disc
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Disc types
+const (
+	DVD    = "dvd"
+	BluRay = "bluray"
+)
+
+// titles shorter are menus and extras, longest title is kept anyway
+const minTitleDuration = 5 * 60
+
+// MaxInfoFile limits size of read IFO and MPLS files
+const MaxInfoFile = 1 << 20
+
+// Disc is DVD or Blu-ray structure in torrent
+type Disc struct {
+	Type string
+	// Root is directory with VIDEO_TS or BDMV, empty for top of torrent
+	Root string
+	// InfoFiles are IFO or MPLS files to read for titles
+	InfoFiles []string
+	// files of disc by upper case name relative to VIDEO_TS or BDMV/STREAM
+	files map[string]string
+}
+
+// Title is main title of disc, its parts are played one after another
+type Title struct {
+	Name     string   `json:"name"`
+	Duration float64  `json:"duration"`
+	Parts    []string `json:"parts"`
+}
+
+// TitlePath is path of title shown as file in torrent
+func TitlePath(root string, t *Title) string {
+	if root == "" {
+		return t.Name
+	}
+	return root + "/" + t.Name
+}
+
+// Find finds disc structures by paths of torrent files
+func Find(paths []string) []*Disc {
+	discs := make(map[string]*Disc)
+	for _, p := range paths {
+		dir, name := path.Split(p)
+		dir = strings.TrimSuffix(dir, "/")
+		parent, base := path.Split(dir)
+		parent = strings.TrimSuffix(parent, "/")
+		name = strings.ToUpper(name)
+		switch strings.ToUpper(base) {
+		case "VIDEO_TS":
+			d := getDisc(discs, DVD, parent)
+			d.files[name] = p
+			if strings.HasPrefix(name, "VTS_") && strings.HasSuffix(name, "_0.IFO") {
+				d.InfoFiles = append(d.InfoFiles, p)
+			}
+		case "STREAM", "PLAYLIST":
+			root, bdmv := path.Split(parent)
+			if strings.ToUpper(bdmv) != "BDMV" {
+				continue
+			}
+			d := getDisc(discs, BluRay, strings.TrimSuffix(root, "/"))
+			if strings.ToUpper(base) == "STREAM" {
+				d.files[name] = p
+			} else if strings.HasSuffix(name, ".MPLS") {
+				d.InfoFiles = append(d.InfoFiles, p)
+			}
+		}
+	}
+	var ret []*Disc
+	for _, d := range discs {
+		sort.Strings(d.InfoFiles)
+		ret = append(ret, d)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Root < ret[j].Root
+	})
+	return ret
+}
+
+func getDisc(discs map[string]*Disc, typ, root string) *Disc {
+	key := typ + ":" + root
+	if d, ok := discs[key]; ok {
+		return d
+	}
+	d := &Disc{Type: typ, Root: root, files: make(map[string]string)}
+	discs[key] = d
+	return d
+}
+
+// Titles parses info files of disc and returns main titles ordered by name,
+// read returns data of file in torrent, wrong info files are skipped
+func (d *Disc) Titles(read func(path string) ([]byte, error)) ([]*Title, error) {
+	var titles []*Title
+	var lastErr error
+	for _, p := range d.InfoFiles {
+		data, err := read(p)
+		if err != nil {
+			return nil, err
+		}
+		var t *Title
+		if d.Type == DVD {
+			t, err = d.parseIFO(path.Base(p), data)
+		} else {
+			t, err = d.parseMPLS(path.Base(p), data)
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if t != nil {
+			titles = append(titles, t)
+		}
+	}
+	if len(titles) == 0 {
+		return nil, lastErr
+	}
+	return mainTitles(titles), nil
+}
+
+// mainTitles removes short and repeated titles
+func mainTitles(titles []*Title) []*Title {
+	longest := titles[0]
+	for _, t := range titles {
+		if t.Duration > longest.Duration {
+			longest = t
+		}
+	}
+	seen := make(map[string]bool)
+	var ret []*Title
+	for _, t := range titles {
+		key := strings.Join(t.Parts, "|")
+		if seen[key] || (t.Duration < minTitleDuration && t != longest) {
+			continue
+		}
+		seen[key] = true
+		ret = append(ret, t)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
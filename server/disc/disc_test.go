@@ -0,0 +1,114 @@
+package disc
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// testIFO builds title set IFO with one program chain of duration
+func testIFO(bcd [4]byte) []byte {
+	data := make([]byte, dvdSector+32)
+	copy(data, "DVDVIDEO-VTS")
+	binary.BigEndian.PutUint32(data[0xCC:], 1)
+	binary.BigEndian.PutUint16(data[dvdSector:], 1)
+	binary.BigEndian.PutUint32(data[dvdSector+12:], 16)
+	copy(data[dvdSector+20:], bcd[:])
+	return data
+}
+
+// testMPLS builds playlist of clips, each 10 minutes long
+func testMPLS(clips ...string) []byte {
+	data := make([]byte, 20)
+	copy(data, "MPLS0200")
+	binary.BigEndian.PutUint32(data[8:], 20)
+	data = append(data, make([]byte, 10)...)
+	binary.BigEndian.PutUint16(data[26:], uint16(len(clips)))
+	for _, clip := range clips {
+		item := make([]byte, 22)
+		binary.BigEndian.PutUint16(item, 20)
+		copy(item[2:], clip+"M2TS")
+		binary.BigEndian.PutUint32(item[14:], 45000)
+		binary.BigEndian.PutUint32(item[18:], 45000+600*45000)
+		data = append(data, item...)
+	}
+	return data
+}
+
+func TestDVD(t *testing.T) {
+	discs := Find([]string{
+		"Movie/VIDEO_TS/VIDEO_TS.IFO",
+		"Movie/VIDEO_TS/VTS_01_0.IFO",
+		"Movie/VIDEO_TS/VTS_01_1.VOB",
+		"Movie/VIDEO_TS/VTS_01_2.VOB",
+		"Movie/VIDEO_TS/VTS_02_0.IFO",
+		"Movie/VIDEO_TS/VTS_02_1.VOB",
+		"Movie/cover.jpg",
+	})
+	if len(discs) != 1 || discs[0].Type != DVD || discs[0].Root != "Movie" || len(discs[0].InfoFiles) != 2 {
+		t.Fatalf("wrong discs %+v", discs)
+	}
+	files := map[string][]byte{
+		"Movie/VIDEO_TS/VTS_01_0.IFO": testIFO([4]byte{0x01, 0x30, 0x00, 0x40}),
+		// menu is shorter than 5 minutes
+		"Movie/VIDEO_TS/VTS_02_0.IFO": testIFO([4]byte{0x00, 0x01, 0x00, 0x40}),
+	}
+	titles, err := discs[0].Titles(func(path string) ([]byte, error) {
+		return files[path], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(titles) != 1 || titles[0].Name != "Title 01.vob" || titles[0].Duration != 5400 || len(titles[0].Parts) != 2 {
+		t.Fatalf("wrong titles %+v", titles)
+	}
+	if p := TitlePath(discs[0].Root, titles[0]); p != "Movie/Title 01.vob" {
+		t.Fatalf("wrong title path %q", p)
+	}
+}
+
+func TestBluRay(t *testing.T) {
+	discs := Find([]string{
+		"BDMV/PLAYLIST/00000.mpls",
+		"BDMV/PLAYLIST/00001.mpls",
+		"BDMV/PLAYLIST/00002.mpls",
+		"BDMV/STREAM/00001.m2ts",
+		"BDMV/STREAM/00002.m2ts",
+	})
+	if len(discs) != 1 || discs[0].Type != BluRay || discs[0].Root != "" {
+		t.Fatalf("wrong discs %+v", discs)
+	}
+	files := map[string][]byte{
+		"BDMV/PLAYLIST/00000.mpls": testMPLS("00001", "00002"),
+		// same clips are repeated title
+		"BDMV/PLAYLIST/00001.mpls": testMPLS("00001", "00002"),
+		// clip is not in torrent
+		"BDMV/PLAYLIST/00002.mpls": testMPLS("00003"),
+	}
+	titles, err := discs[0].Titles(func(path string) ([]byte, error) {
+		return files[path], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(titles) != 1 || titles[0].Duration != 1200 || len(titles[0].Parts) != 2 || titles[0].Parts[1] != "BDMV/STREAM/00002.m2ts" {
+		t.Fatalf("wrong titles %+v", titles)
+	}
+
+	_, err = discs[0].Titles(func(path string) ([]byte, error) {
+		return nil, errors.New("read error")
+	})
+	if err == nil {
+		t.Fatal("no read error")
+	}
+}
+
+func FuzzInfoFiles(f *testing.F) {
+	f.Add(testIFO([4]byte{0x01, 0x30, 0x00, 0x40}))
+	f.Add(testMPLS("00001", "00002"))
+	d := &Disc{files: map[string]string{"VTS_01_1.VOB": "VTS_01_1.VOB", "00001.M2TS": "00001.m2ts"}}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d.parseIFO("VTS_01_0.IFO", data)
+		d.parseMPLS("00000.mpls", data)
+	})
+}
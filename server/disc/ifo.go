@@ -0,0 +1,66 @@
+package disc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const dvdSector = 2048
+
+// parseIFO reads longest program chain of DVD title set, title set is
+// played as its VOB files VTS_NN_1.VOB, VTS_NN_2.VOB...
+func (d *Disc) parseIFO(name string, data []byte) (*Title, error) {
+	if len(data) < 0xD0 || string(data[:12]) != "DVDVIDEO-VTS" {
+		return nil, errors.New("wrong vts ifo " + name)
+	}
+	set := strings.TrimSuffix(strings.ToUpper(name), "_0.IFO")
+	t := &Title{Name: "Title " + strings.TrimPrefix(set, "VTS_") + ".vob"}
+	for i := 1; i <= 9; i++ {
+		p, ok := d.files[fmt.Sprintf("%s_%d.VOB", set, i)]
+		if !ok {
+			break
+		}
+		t.Parts = append(t.Parts, p)
+	}
+	if len(t.Parts) == 0 {
+		return nil, nil
+	}
+
+	// offsets are 32 bit, they overflow int on 32 bit platforms
+	size := int64(len(data))
+	pgcit := int64(binary.BigEndian.Uint32(data[0xCC:])) * dvdSector
+	if pgcit == 0 || pgcit+8 > size {
+		return t, nil
+	}
+	count := int64(binary.BigEndian.Uint16(data[pgcit:]))
+	for i := int64(0); i < count; i++ {
+		srp := pgcit + 8 + i*8
+		if srp+8 > size {
+			break
+		}
+		pgc := pgcit + int64(binary.BigEndian.Uint32(data[srp+4:]))
+		if pgc+8 > size {
+			continue
+		}
+		if dur := dvdTime(data[pgc+4 : pgc+8]); dur > t.Duration {
+			t.Duration = dur
+		}
+	}
+	return t, nil
+}
+
+// dvdTime decodes BCD playback time hh:mm:ss:ff, frame rate is in two high
+// bits of frames
+func dvdTime(b []byte) float64 {
+	bcd := func(v byte) float64 {
+		return float64(v>>4*10 + v&0x0F)
+	}
+	secs := bcd(b[0])*3600 + bcd(b[1])*60 + bcd(b[2])
+	fps := 25.0
+	if b[3]>>6 == 3 {
+		fps = 29.97
+	}
+	return secs + bcd(b[3]&0x3F)/fps
+}
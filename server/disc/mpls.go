@@ -0,0 +1,47 @@
+package disc
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// parseMPLS reads play items of Blu-ray playlist, each item is whole clip
+// from BDMV/STREAM, angles besides first are skipped
+func (d *Disc) parseMPLS(name string, data []byte) (*Title, error) {
+	if len(data) < 20 || string(data[:4]) != "MPLS" {
+		return nil, errors.New("wrong mpls " + name)
+	}
+	// offset is 32 bit, it overflows int on 32 bit platforms
+	start := int64(binary.BigEndian.Uint32(data[8:]))
+	if start+10 > int64(len(data)) {
+		return nil, errors.New("wrong mpls " + name)
+	}
+	pl := int(start)
+	items := int(binary.BigEndian.Uint16(data[pl+6:]))
+	t := &Title{Name: "Title " + strings.TrimSuffix(strings.ToUpper(name), ".MPLS") + ".m2ts"}
+	off := pl + 10
+	for i := 0; i < items; i++ {
+		if off+22 > len(data) {
+			return nil, errors.New("short mpls " + name)
+		}
+		size := int(binary.BigEndian.Uint16(data[off:]))
+		clip := string(data[off+2 : off+7])
+		in := binary.BigEndian.Uint32(data[off+14:])
+		out := binary.BigEndian.Uint32(data[off+18:])
+		p, ok := d.files[clip+".M2TS"]
+		if !ok {
+			// clip is not in torrent
+			return nil, nil
+		}
+		t.Parts = append(t.Parts, p)
+		if out > in {
+			t.Duration += float64(out-in) / 45000
+		}
+		off += 2 + size
+	}
+	if len(t.Parts) == 0 {
+		return nil, nil
+	}
+	return t, nil
+}
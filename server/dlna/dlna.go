@@ -15,6 +15,7 @@ import (
 	"github.com/anacrolix/log"
 
 	"server/settings"
+	"server/utils"
 	"server/web/pages/template"
 )
 
@@ -22,45 +23,15 @@ var dmsServer *dms.Server
 
 func Start() {
 	logger := log.Default.WithNames("dlna")
+	ifs, conn, err := listen(logger)
+	if err != nil {
+		logger.Levelf(log.Error, "error start dlna: %v", err)
+		return
+	}
 	dmsServer = &dms.Server{
-		Logger: logger.WithNames("dms", "server"),
-		Interfaces: func() (ifs []net.Interface) {
-			var err error
-			ifaces, err := net.Interfaces()
-			if err != nil {
-				logger.Levelf(log.Error, "%v", err)
-				os.Exit(1)
-			}
-			for _, i := range ifaces {
-				// interface flags seem to always be 0 on Windows
-				if runtime.GOOS != "windows" && (i.Flags&net.FlagLoopback != 0 || i.Flags&net.FlagUp == 0 || i.Flags&net.FlagMulticast == 0) {
-					continue
-				}
-				ifs = append(ifs, i)
-			}
-			return
-		}(),
-		HTTPConn: func() net.Listener {
-			port := 9080
-			for {
-				logger.Levelf(log.Info, "Check dlna port %d", port)
-				m, err := net.Listen("tcp", ":"+strconv.Itoa(port))
-				if m != nil {
-					m.Close()
-				}
-				if err == nil {
-					break
-				}
-				port++
-			}
-			logger.Levelf(log.Info, "Set dlna port %d", port)
-			conn, err := net.Listen("tcp", ":"+strconv.Itoa(port))
-			if err != nil {
-				logger.Levelf(log.Error, "%v", err)
-				os.Exit(1)
-			}
-			return conn
-		}(),
+		Logger:              logger.WithNames("dms", "server"),
+		Interfaces:          ifs,
+		HTTPConn:            conn,
 		FriendlyName:        getDefaultFriendlyName(),
 		NoTranscode:         true,
 		NoProbe:             true,
@@ -107,6 +78,71 @@ func Start() {
 	}()
 }
 
+// listen returns interfaces and http listener of dlna, bound to DLNABind if set
+func listen(logger log.Logger) ([]net.Interface, net.Listener, error) {
+	bind := settings.BTsets.DLNABind
+	if bind != "" {
+		iface, err := utils.BindInterface(bind)
+		if err != nil {
+			return nil, nil, err
+		}
+		ip, err := utils.ResolveBind(bind)
+		if err != nil {
+			return nil, nil, err
+		}
+		addr := net.JoinHostPort(ip, "9080")
+		conn, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.Levelf(log.Info, "Set dlna addr %s", addr)
+		if iface == nil {
+			return allInterfaces(), conn, nil
+		}
+		return []net.Interface{*iface}, conn, nil
+	}
+
+	port := 9080
+	for {
+		logger.Levelf(log.Info, "Check dlna port %d", port)
+		m, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+		if m != nil {
+			m.Close()
+		}
+		if err == nil {
+			break
+		}
+		port++
+	}
+	logger.Levelf(log.Info, "Set dlna port %d", port)
+	conn, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return nil, nil, err
+	}
+	return allInterfaces(), conn, nil
+}
+
+func allInterfaces() (ifs []net.Interface) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		log.Default.WithNames("dlna").Levelf(log.Error, "%v", err)
+		return
+	}
+	for _, i := range ifaces {
+		// interface flags seem to always be 0 on Windows
+		if runtime.GOOS != "windows" && (i.Flags&net.FlagLoopback != 0 || i.Flags&net.FlagUp == 0 || i.Flags&net.FlagMulticast == 0) {
+			continue
+		}
+		ifs = append(ifs, i)
+	}
+	return
+}
+
+// Running reports whether dlna server is started
+func Running() bool {
+	return dmsServer != nil
+}
+
 func Stop() {
 	if dmsServer != nil {
 		dmsServer.Close()
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/anacrolix/dms/dlna"
+	"github.com/anacrolix/dms/misc"
 	"github.com/anacrolix/dms/upnpav"
 
 	"server/log"
@@ -17,8 +18,11 @@ import (
 	"server/settings"
 	"server/torr"
 	"server/torr/state"
+	"server/web/auth"
 )
 
+var dlnaLog = log.Component("dlna")
+
 func getRoot() (ret []interface{}) {
 
 	// Torrents Object
@@ -211,7 +215,7 @@ func loadTorrent(path, host string) (ret []interface{}) {
 
 	tor := torr.GetTorrent(hash)
 	if tor == nil {
-		log.TLogln("Dlna error get info from torrent", hash)
+		dlnaLog.Warn("error get info from torrent", "hash", hash)
 		return
 	}
 	if len(tor.Files()) == 0 {
@@ -254,18 +258,14 @@ func getObjFromTorrent(path, parent, host string, torr *torr.Torrent, file *stat
 
 	mime, err := mt.MimeTypeByPath(file.Path)
 	if err != nil {
-		if settings.BTsets.EnableDebug {
-			log.TLogln("Can't detect mime type", err)
-		}
+		dlnaLog.Debug("can't detect mime type", "path", file.Path, "error", err)
 		return
 	}
 	// TODO: handle subtitles for media
 	if !mime.IsMedia() {
 		return
 	}
-	if settings.BTsets.EnableDebug {
-		log.TLogln("mime type", mime.String(), file.Path)
-	}
+	dlnaLog.Debug("mime type", "mime", mime.String(), "path", file.Path)
 
 	obj := upnpav.Object{
 		ID:         parent + "%2F" + url.PathEscape(file.Path),
@@ -280,14 +280,26 @@ func getObjFromTorrent(path, parent, host string, torr *torr.Torrent, file *stat
 		Object: obj,
 		Res:    make([]upnpav.Resource, 0, 1),
 	}
-	pathPlay := "stream/" + url.PathEscape(file.Path) + "?link=" + torr.TorrentSpec.InfoHash.HexString() + "&play&index=" + strconv.Itoa(file.Id)
-	item.Res = append(item.Res, upnpav.Resource{
+	pathPlay := "stream/" + url.PathEscape(file.Path) + "?link=" + torr.TorrentSpec.InfoHash.HexString() + "&play&index=" + strconv.Itoa(file.Id) +
+		auth.SignStream(settings.DLNAUser, torr.TorrentSpec.InfoHash.HexString(), strconv.Itoa(file.Id))
+	res := upnpav.Resource{
 		URL: getLink(host, pathPlay),
 		ProtocolInfo: fmt.Sprintf("http-get:*:%s:%s", mime, dlna.ContentFeatures{
 			SupportRange:    true,
 			SupportTimeSeek: true,
 		}.String()),
 		Size: uint64(file.Length),
-	})
+	}
+	if m := file.Media; m != nil {
+		if m.Duration > 0 {
+			res.Duration = misc.FormatDurationSexagesimal(time.Duration(m.Duration * float64(time.Second)))
+		}
+		if m.Width > 0 {
+			res.Resolution = fmt.Sprintf("%dx%d", m.Width, m.Height)
+		}
+		// bytes per second in DLNA
+		res.Bitrate = uint(m.Bitrate / 8)
+	}
+	item.Res = append(item.Res, res)
 	return item
 }
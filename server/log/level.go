@@ -0,0 +1,236 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// ParseLevel parses level name, empty is info
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, errors.New("unknown log level: " + s)
+}
+
+// Log output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var (
+	defLevel   = LevelInfo
+	compLevels = make(map[string]Level)
+	jsonFormat bool
+	muLevels   sync.RWMutex
+)
+
+// SetLevels sets default level and levels of components
+func SetLevels(def string, components map[string]string) error {
+	lvl, err := ParseLevel(def)
+	if err != nil {
+		return err
+	}
+	comps := make(map[string]Level, len(components))
+	for name, s := range components {
+		if comps[name], err = ParseLevel(s); err != nil {
+			return err
+		}
+	}
+	muLevels.Lock()
+	defLevel = lvl
+	compLevels = comps
+	muLevels.Unlock()
+	return nil
+}
+
+// CheckLevels validates level names
+func CheckLevels(def string, components map[string]string) error {
+	if _, err := ParseLevel(def); err != nil {
+		return err
+	}
+	for _, s := range components {
+		if _, err := ParseLevel(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckFormat validates output format, empty is text
+func CheckFormat(format string) error {
+	if format != "" && format != FormatText && format != FormatJSON {
+		return errors.New("unknown log format: " + format)
+	}
+	return nil
+}
+
+// SetFormat sets text or json output
+func SetFormat(format string) error {
+	if err := CheckFormat(format); err != nil {
+		return err
+	}
+	muLevels.Lock()
+	jsonFormat = format == FormatJSON
+	muLevels.Unlock()
+	return nil
+}
+
+// Logger writes leveled messages of component with fields
+type Logger struct {
+	component string
+	fields    []string
+}
+
+// Component returns logger of server part, e.g. dlna, stream, web
+func Component(name string) *Logger {
+	return &Logger{component: name}
+}
+
+// With returns logger with added key and value pairs, e.g. "hash", hash
+func (l *Logger) With(kv ...interface{}) *Logger {
+	ret := &Logger{component: l.component}
+	ret.fields = append(append(ret.fields, l.fields...), pairs(kv)...)
+	return ret
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	muLevels.RLock()
+	defer muLevels.RUnlock()
+	minLevel, ok := compLevels[l.component]
+	if !ok {
+		minLevel = defLevel
+	}
+	return level >= minLevel
+}
+
+func (l *Logger) Debug(msg string, kv ...interface{}) {
+	l.log(LevelDebug, msg, kv)
+}
+
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.log(LevelInfo, msg, kv)
+}
+
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.log(LevelWarn, msg, kv)
+}
+
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.log(LevelError, msg, kv)
+}
+
+func (l *Logger) log(level Level, msg string, kv []interface{}) {
+	if l.Enabled(level) {
+		l.write(level, msg, kv)
+	}
+}
+
+func (l *Logger) write(level Level, msg string, kv []interface{}) {
+	e := &Entry{
+		Time:      time.Now(),
+		Level:     level.String(),
+		Component: l.component,
+		Msg:       msg,
+	}
+	fields := append(append([]string{}, l.fields...), pairs(kv)...)
+	if len(fields) > 0 {
+		e.Fields = make(map[string]string, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			e.Fields[fields[i]] = fields[i+1]
+		}
+	}
+	ring.add(e)
+	output(e)
+}
+
+func output(e *Entry) {
+	muLevels.RLock()
+	isJSON := jsonFormat
+	muLevels.RUnlock()
+	if isJSON {
+		m := map[string]interface{}{
+			"time":  e.Time.Format(time.RFC3339Nano),
+			"level": e.Level,
+			"msg":   e.Msg,
+		}
+		if e.Component != "" {
+			m["component"] = e.Component
+		}
+		for k, v := range e.Fields {
+			if _, ok := m[k]; !ok {
+				m[k] = v
+			}
+		}
+		buf, _ := json.Marshal(m)
+		log.Writer().Write(append(buf, '\n'))
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.ToUpper(e.Level))
+	if e.Component != "" {
+		sb.WriteString(" [" + e.Component + "]")
+	}
+	sb.WriteString(" " + e.Msg)
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := e.Fields[k]
+		if strings.ContainsAny(v, " \"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		sb.WriteString(" " + k + "=" + v)
+	}
+	log.Println(sb.String())
+}
+
+func pairs(kv []interface{}) []string {
+	ret := make([]string, 0, len(kv)+1)
+	for _, v := range kv {
+		ret = append(ret, fmt.Sprint(v))
+	}
+	if len(ret)%2 != 0 {
+		ret = append(ret, "")
+	}
+	return ret
+}
@@ -17,15 +17,15 @@ var webLogPath = ""
 
 var webLog *log.Logger
 
-var logFile *os.File
-var webLogFile *os.File
+var logFile *rotateFile
+var webLogFile *rotateFile
 
 func Init(path, webpath string) {
 	webLogPath = webpath
 	logPath = path
 
 	if webpath != "" {
-		ff, err := os.OpenFile(webLogPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+		ff, err := openRotate(webLogPath, nil)
 		if err != nil {
 			TLogln("Error create web log file:", err)
 		} else {
@@ -35,19 +35,15 @@ func Init(path, webpath string) {
 	}
 
 	if path != "" {
-		if fi, err := os.Lstat(path); err == nil {
-			if fi.Size() >= 100*1024*1024 { // 100MB
-				os.Remove(path)
-			}
-		}
-		ff, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+		ff, err := openRotate(path, func(f *os.File) {
+			os.Stdout = f
+			os.Stderr = f
+		})
 		if err != nil {
 			TLogln("Error create log file:", err)
 			return
 		}
 		logFile = ff
-		os.Stdout = ff
-		os.Stderr = ff
 		var timeFmt string
 		var ok bool
 		timeFmt, ok = os.LookupEnv("GO_LOG_TIME_FMT")
@@ -61,6 +57,23 @@ func Init(path, webpath string) {
 	}
 }
 
+// Rotate starts new server and web log files
+func Rotate() error {
+	var err error
+	if logFile != nil {
+		err = logFile.Rotate()
+	}
+	if webLogFile != nil {
+		if werr := webLogFile.Rotate(); err == nil {
+			err = werr
+		}
+	}
+	if err == nil {
+		TLogln("Logs rotated")
+	}
+	return err
+}
+
 func Close() {
 	if logFile != nil {
 		logFile.Close()
@@ -70,8 +83,15 @@ func Close() {
 	}
 }
 
+var (
+	std    = Component("")
+	webStd = Component("web")
+)
+
+// TLogln writes info message without component, it's not filtered by level
+// as errors of old code are logged with it
 func TLogln(v ...interface{}) {
-	log.Println(v...)
+	std.write(LevelInfo, strings.TrimSuffix(fmt.Sprintln(v...), "\n"), nil)
 }
 
 func WebLogln(v ...interface{}) {
@@ -84,6 +104,10 @@ func WebLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if webLog == nil {
 			c.Next()
+			if webStd.Enabled(LevelDebug) {
+				webStd.Debug("request", "client", c.ClientIP(), "method", c.Request.Method,
+					"path", c.Request.URL.Path, "status", c.Writer.Status())
+			}
 			return
 		}
 		body := ""
@@ -113,5 +137,6 @@ func WebLogger() gin.HandlerFunc {
 			string(body),
 		)
 		WebLogln(logStr)
+		webStd.Debug("request", "client", clientIP, "method", method, "path", path, "status", statusCode)
 	}
 }
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const ringSize = 2000
+
+// Entry is log message kept in memory for web viewer
+type Entry struct {
+	Seq       int64             `json:"seq"`
+	Time      time.Time         `json:"time"`
+	Level     string            `json:"level"`
+	Component string            `json:"component,omitempty"`
+	Msg       string            `json:"msg"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// Filter of recent entries, empty fields match all
+type Filter struct {
+	Since     int64 // entries after seq
+	Level     string
+	Component string
+	Hash      string
+	Client    string
+	Query     string // substring of message or fields
+	Limit     int    // last entries, def 200
+}
+
+type ringBuffer struct {
+	entries []*Entry
+	next    int
+	seq     int64
+	mu      sync.Mutex
+}
+
+var ring = &ringBuffer{entries: make([]*Entry, 0, ringSize)}
+
+func (r *ringBuffer) add(e *Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	e.Seq = r.seq
+	if len(r.entries) < ringSize {
+		r.entries = append(r.entries, e)
+		return
+	}
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % ringSize
+}
+
+// Recent returns matched entries from memory, oldest first
+func Recent(f Filter) []*Entry {
+	minLevel, err := ParseLevel(f.Level)
+	if err != nil || f.Level == "" {
+		minLevel = LevelDebug
+	}
+	if f.Limit <= 0 {
+		f.Limit = 200
+	}
+	query := strings.ToLower(f.Query)
+
+	ring.mu.Lock()
+	list := make([]*Entry, 0, len(ring.entries))
+	list = append(list, ring.entries[ring.next:]...)
+	list = append(list, ring.entries[:ring.next]...)
+	ring.mu.Unlock()
+
+	ret := make([]*Entry, 0)
+	for _, e := range list {
+		if e.Seq <= f.Since {
+			continue
+		}
+		if lvl, _ := ParseLevel(e.Level); lvl < minLevel {
+			continue
+		}
+		if f.Component != "" && e.Component != f.Component {
+			continue
+		}
+		if f.Hash != "" && !strings.EqualFold(e.Fields["hash"], f.Hash) {
+			continue
+		}
+		if f.Client != "" && e.Fields["client"] != f.Client {
+			continue
+		}
+		if query != "" && !entryContains(e, query) {
+			continue
+		}
+		ret = append(ret, e)
+	}
+	if len(ret) > f.Limit {
+		ret = ret[len(ret)-f.Limit:]
+	}
+	return ret
+}
+
+func entryContains(e *Entry, query string) bool {
+	if strings.Contains(strings.ToLower(e.Msg), query) {
+		return true
+	}
+	for _, v := range e.Fields {
+		if strings.Contains(strings.ToLower(v), query) {
+			return true
+		}
+	}
+	return false
+}
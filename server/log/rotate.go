@@ -0,0 +1,188 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotateOptions of server and web logs
+type RotateOptions struct {
+	MaxSize  int64         // bytes, 0 - don't rotate by size
+	MaxFiles int           // rotated files to keep, 0 - all
+	MaxAge   time.Duration // remove older rotated files, 0 - keep
+	Compress bool          // gzip rotated files
+}
+
+var (
+	rotateOpts = RotateOptions{MaxSize: 100 * 1024 * 1024, MaxFiles: 2}
+	muClean    sync.Mutex
+)
+
+// SetRotate sets rotate options, must be called before Init
+func SetRotate(opts RotateOptions) {
+	rotateOpts = opts
+}
+
+// rotateFile is log file that is renamed with time suffix when it grows
+// over max size or on request
+type rotateFile struct {
+	path   string
+	file   *os.File
+	size   int64
+	onOpen func(*os.File)
+	mu     sync.Mutex
+}
+
+func openRotate(path string, onOpen func(*os.File)) (*rotateFile, error) {
+	r := &rotateFile{path: path, onOpen: onOpen}
+	if fi, err := os.Stat(path); err == nil && rotateOpts.MaxSize > 0 && fi.Size() >= rotateOpts.MaxSize {
+		r.backup()
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	go r.clean()
+	return r, nil
+}
+
+func (r *rotateFile) open() error {
+	ff, err := os.OpenFile(r.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	r.size = 0
+	if fi, err := ff.Stat(); err == nil {
+		r.size = fi.Size()
+	}
+	r.file = ff
+	if r.onOpen != nil {
+		r.onOpen(ff)
+	}
+	return nil
+}
+
+func (r *rotateFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if rotateOpts.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > rotateOpts.MaxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate closes current file and starts new one
+func (r *rotateFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	return r.rotate()
+}
+
+func (r *rotateFile) rotate() error {
+	// close before rename, windows can't rename opened file
+	r.file.Close()
+	r.file = nil
+	r.backup()
+	if err := r.open(); err != nil {
+		return err
+	}
+	go r.clean()
+	return nil
+}
+
+func (r *rotateFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// backup renames log file, it is compressed later in clean
+func (r *rotateFile) backup() {
+	name := r.path + "." + time.Now().Format("20060102-150405.000")
+	os.Rename(r.path, name)
+}
+
+// clean compresses new backups and removes extra and old ones
+func (r *rotateFile) clean() {
+	muClean.Lock()
+	defer muClean.Unlock()
+	matches, _ := filepath.Glob(r.path + ".*")
+	var backups []string
+	for _, name := range matches {
+		suffix := strings.TrimPrefix(name, r.path+".")
+		if suffix == "" || suffix[0] < '0' || suffix[0] > '9' || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		if rotateOpts.Compress && !strings.HasSuffix(name, ".gz") {
+			if gz, err := compress(name); err == nil {
+				name = gz
+			}
+		}
+		backups = append(backups, name)
+	}
+	// names have time suffix, newest last
+	sort.Strings(backups)
+	for i, name := range backups {
+		remove := rotateOpts.MaxFiles > 0 && i < len(backups)-rotateOpts.MaxFiles
+		if !remove && rotateOpts.MaxAge > 0 {
+			if fi, err := os.Stat(name); err == nil && time.Since(fi.ModTime()) > rotateOpts.MaxAge {
+				remove = true
+			}
+		}
+		if remove {
+			os.Remove(name)
+		}
+	}
+}
+
+func compress(name string) (string, error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	tmp := name + ".gz.tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err = os.Rename(tmp, name+".gz"); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	src.Close()
+	os.Remove(name)
+	return name + ".gz", nil
+}
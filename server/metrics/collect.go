@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	HTTPDuration    = NewHistogram(.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60)
+	ResolveDuration = NewHistogram(.5, 1, 2, 5, 10, 20, 30, 60, 120, 300)
+	CacheEvictions  Counter
+	Streams         Gauge
+	StreamsTotal    Counter
+)
+
+// WebMetrics is middleware that observes request durations per route
+func WebMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		HTTPDuration.Observe(time.Since(start).Seconds(),
+			"route", route, "method", c.Request.Method, "code", strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// WriteGlobal writes server wide metrics and go runtime stats
+func WriteGlobal(w *Writer) {
+	w.Counter("torrserver_cache_evictions_total", "Pieces removed from cache to free space.", float64(CacheEvictions.Value()))
+	w.Gauge("torrserver_streams_active", "Streams in progress.", float64(Streams.Value()))
+	w.Counter("torrserver_streams_total", "Streams started.", float64(StreamsTotal.Value()))
+	w.Histogram("torrserver_metadata_resolve_seconds", "Time to get torrent info after add.", ResolveDuration)
+	w.Histogram("torrserver_http_request_duration_seconds", "Duration of http requests by route.", HTTPDuration)
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	w.Gauge("go_goroutines", "Number of goroutines.", float64(runtime.NumGoroutine()))
+	w.Gauge("go_memstats_alloc_bytes", "Bytes of allocated heap objects.", float64(ms.Alloc))
+	w.Gauge("go_memstats_heap_inuse_bytes", "Bytes in in-use heap spans.", float64(ms.HeapInuse))
+	w.Gauge("go_memstats_sys_bytes", "Bytes obtained from system.", float64(ms.Sys))
+	w.Counter("go_memstats_mallocs_total", "Heap objects allocated.", float64(ms.Mallocs))
+	w.Counter("go_gc_cycles_total", "Completed GC cycles.", float64(ms.NumGC))
+	w.Counter("go_gc_pause_seconds_total", "Total GC stop-the-world pause.", float64(ms.PauseTotalNs)/1e9)
+}
@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is monotonic value
+type Counter struct {
+	v int64
+}
+
+func (c *Counter) Inc() {
+	atomic.AddInt64(&c.v, 1)
+}
+
+func (c *Counter) Add(n int64) {
+	atomic.AddInt64(&c.v, n)
+}
+
+func (c *Counter) Value() int64 {
+	return atomic.LoadInt64(&c.v)
+}
+
+// Gauge is value that may go up and down
+type Gauge struct {
+	v int64
+}
+
+func (g *Gauge) Add(n int64) {
+	atomic.AddInt64(&g.v, n)
+}
+
+func (g *Gauge) Value() int64 {
+	return atomic.LoadInt64(&g.v)
+}
+
+// Histogram counts observations in buckets, separately for each label set
+type Histogram struct {
+	buckets []float64
+	series  map[string]*histSeries
+	mu      sync.Mutex
+}
+
+type histSeries struct {
+	labels []string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func NewHistogram(buckets ...float64) *Histogram {
+	sort.Float64s(buckets)
+	return &Histogram{
+		buckets: buckets,
+		series:  make(map[string]*histSeries),
+	}
+}
+
+// Observe adds value, labels are name and value pairs
+func (h *Histogram) Observe(v float64, labels ...string) {
+	key := strings.Join(labels, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &histSeries{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// Writer writes metrics in prometheus text format, values of one metric
+// must be written together
+type Writer struct {
+	w     io.Writer
+	names map[string]bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, names: make(map[string]bool)}
+}
+
+func (w *Writer) Gauge(name, help string, v float64, labels ...string) {
+	w.header(name, help, "gauge")
+	w.value(name, v, labels)
+}
+
+func (w *Writer) Counter(name, help string, v float64, labels ...string) {
+	w.header(name, help, "counter")
+	w.value(name, v, labels)
+}
+
+func (w *Writer) Histogram(name, help string, h *Histogram) {
+	w.header(name, help, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := h.series[k]
+		for i, b := range h.buckets {
+			w.value(name+"_bucket", float64(s.counts[i]), withLabel(s.labels, "le", formatFloat(b)))
+		}
+		w.value(name+"_bucket", float64(s.count), withLabel(s.labels, "le", "+Inf"))
+		w.value(name+"_sum", s.sum, s.labels)
+		w.value(name+"_count", float64(s.count), s.labels)
+	}
+}
+
+func (w *Writer) header(name, help, typ string) {
+	if w.names[name] {
+		return
+	}
+	w.names[name] = true
+	fmt.Fprintf(w.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (w *Writer) value(name string, v float64, labels []string) {
+	io.WriteString(w.w, name)
+	if len(labels) > 1 {
+		io.WriteString(w.w, "{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				io.WriteString(w.w, ",")
+			}
+			io.WriteString(w.w, labels[i]+"=\""+labelEscaper.Replace(labels[i+1])+"\"")
+		}
+		io.WriteString(w.w, "}")
+	}
+	io.WriteString(w.w, " "+formatFloat(v)+"\n")
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func withLabel(labels []string, name, value string) []string {
+	ret := make([]string, 0, len(labels)+2)
+	ret = append(ret, labels...)
+	return append(ret, name, value)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
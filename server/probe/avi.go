@@ -0,0 +1,190 @@
+package probe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var aviVideoCodecs = map[string]string{
+	"xvid": "mpeg4",
+	"divx": "mpeg4",
+	"dx50": "mpeg4",
+	"fmp4": "mpeg4",
+	"mp4v": "mpeg4",
+	"div3": "msmpeg4v3",
+	"h264": "h264",
+	"x264": "h264",
+	"avc1": "h264",
+	"hevc": "hevc",
+	"h265": "hevc",
+	"hvc1": "hevc",
+	"mjpg": "mjpeg",
+	"wmv3": "wmv3",
+	"mpg2": "mpeg2video",
+}
+
+var aviAudioCodecs = map[uint16]string{
+	0x0001: "pcm",
+	0x0050: "mp2",
+	0x0055: "mp3",
+	0x00FF: "aac",
+	0x0161: "wmav2",
+	0x1610: "aac",
+	0x2000: "ac3",
+	0x2001: "dts",
+}
+
+type aviStream struct {
+	track               *Track
+	scale, rate, length uint32
+}
+
+func parseAVI(s *source) (*Info, error) {
+	info := &Info{Format: "avi"}
+	var usPerFrame, totalFrames, dmlFrames uint32
+	var streams []*aviStream
+
+	// RIFF AVI header is followed by chunks, hdrl list is first
+	off := int64(12)
+	for i := 0; off+8 <= s.size && i < 100; i++ {
+		hdr, err := s.readAt(off, 12)
+		if len(hdr) < 12 {
+			return nil, shortErr(err)
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		if string(hdr[:4]) == "LIST" && string(hdr[8:12]) == "movi" {
+			break
+		}
+		if string(hdr[:4]) == "LIST" && string(hdr[8:12]) == "hdrl" && size >= 4 {
+			body, err := s.readAt(off+12, size-4)
+			if err != nil {
+				return nil, err
+			}
+			riffChunks(body, func(id string, data []byte) {
+				switch id {
+				case "avih":
+					if len(data) >= 40 {
+						usPerFrame = binary.LittleEndian.Uint32(data)
+						totalFrames = binary.LittleEndian.Uint32(data[16:])
+						info.Width = int(binary.LittleEndian.Uint32(data[32:]))
+						info.Height = int(binary.LittleEndian.Uint32(data[36:]))
+					}
+				case "strl":
+					if st := parseStrl(data); st != nil {
+						streams = append(streams, st)
+					}
+				case "odml":
+					riffChunks(data, func(id string, data []byte) {
+						if id == "dmlh" && len(data) >= 4 {
+							dmlFrames = binary.LittleEndian.Uint32(data)
+						}
+					})
+				}
+			})
+			break
+		}
+		off += 8 + size + size&1
+	}
+	if len(streams) == 0 {
+		return nil, errors.New("avi header not found")
+	}
+	for _, st := range streams {
+		info.Tracks = append(info.Tracks, st.track)
+		if st.track.Type == TrackVideo && info.Duration == 0 && st.rate > 0 {
+			info.Duration = float64(st.length) * float64(st.scale) / float64(st.rate)
+		}
+	}
+	if info.Duration == 0 {
+		// avih counts frames of first RIFF only, OpenDML header has all
+		if dmlFrames > totalFrames {
+			totalFrames = dmlFrames
+		}
+		info.Duration = float64(totalFrames) * float64(usPerFrame) / 1e6
+	}
+	return info, nil
+}
+
+func parseStrl(data []byte) *aviStream {
+	st := &aviStream{track: &Track{}}
+	var fccType, handler string
+	riffChunks(data, func(id string, data []byte) {
+		switch id {
+		case "strh":
+			if len(data) >= 36 {
+				fccType = string(data[:4])
+				handler = strings.ToLower(strings.TrimRight(string(data[4:8]), "\x00 "))
+				st.scale = binary.LittleEndian.Uint32(data[20:])
+				st.rate = binary.LittleEndian.Uint32(data[24:])
+				st.length = binary.LittleEndian.Uint32(data[32:])
+			}
+		case "strf":
+			switch fccType {
+			case "vids":
+				if len(data) >= 20 {
+					st.track.Width = int(int32(binary.LittleEndian.Uint32(data[4:])))
+					height := int32(binary.LittleEndian.Uint32(data[8:]))
+					if height < 0 {
+						height = -height
+					}
+					st.track.Height = int(height)
+					if fourcc := strings.ToLower(strings.TrimRight(string(data[16:20]), "\x00 ")); fourcc != "" {
+						handler = fourcc
+					}
+				}
+			case "auds":
+				if len(data) >= 8 {
+					tag := binary.LittleEndian.Uint16(data)
+					if codec, ok := aviAudioCodecs[tag]; ok {
+						st.track.Codec = codec
+					} else {
+						st.track.Codec = fmt.Sprintf("0x%04x", tag)
+					}
+					st.track.Channels = int(binary.LittleEndian.Uint16(data[2:]))
+					st.track.SampleRate = int(binary.LittleEndian.Uint32(data[4:]))
+				}
+			}
+		case "strn":
+			st.track.Title = strings.TrimRight(string(data), "\x00")
+		}
+	})
+	switch fccType {
+	case "vids":
+		st.track.Type = TrackVideo
+		if codec, ok := aviVideoCodecs[handler]; ok {
+			st.track.Codec = codec
+		} else {
+			st.track.Codec = handler
+		}
+	case "auds":
+		st.track.Type = TrackAudio
+	case "txts":
+		st.track.Type = TrackSubtitle
+	default:
+		return nil
+	}
+	return st
+}
+
+// riffChunks calls fn for each chunk in data, for LIST with its list type
+func riffChunks(data []byte, fn func(id string, data []byte)) {
+	for len(data) >= 8 {
+		id := string(data[:4])
+		size := int(binary.LittleEndian.Uint32(data[4:]))
+		if size > len(data)-8 {
+			size = len(data) - 8
+		}
+		body := data[8 : 8+size]
+		if id == "LIST" && len(body) >= 4 {
+			fn(string(body[:4]), body[4:])
+		} else {
+			fn(id, body)
+		}
+		next := 8 + size + size&1
+		if next > len(data) {
+			return
+		}
+		data = data[next:]
+	}
+}
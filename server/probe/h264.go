@@ -0,0 +1,180 @@
+package probe
+
+import (
+	"bytes"
+	"errors"
+)
+
+// h264Size finds sequence parameter set in annex b stream and returns
+// picture size, ok is false if more data is needed
+func h264Size(data []byte) (int, int, bool) {
+	start := []byte{0, 0, 1}
+	for {
+		i := bytes.Index(data, start)
+		if i < 0 || i+3 >= len(data) {
+			return 0, 0, false
+		}
+		data = data[i+3:]
+		if data[0]&0x1F != 7 {
+			continue
+		}
+		end := bytes.Index(data, start)
+		if end < 0 {
+			if len(data) < 256 {
+				return 0, 0, false
+			}
+			end = len(data)
+		}
+		w, h, err := parseSPS(unescapeRBSP(data[1:end]))
+		return w, h, err == nil && w > 0 && h > 0
+	}
+}
+
+// unescapeRBSP removes emulation prevention bytes, 00 00 03 is 00 00
+func unescapeRBSP(data []byte) []byte {
+	ret := make([]byte, 0, len(data))
+	zeros := 0
+	for _, b := range data {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		ret = append(ret, b)
+	}
+	return ret
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+	err  bool
+}
+
+func (r *bitReader) bit() uint {
+	if r.pos >= len(r.data)*8 {
+		r.err = true
+		return 0
+	}
+	b := r.data[r.pos/8] >> (7 - uint(r.pos%8)) & 1
+	r.pos++
+	return uint(b)
+}
+
+func (r *bitReader) bits(n int) uint {
+	var v uint
+	for i := 0; i < n; i++ {
+		v = v<<1 | r.bit()
+	}
+	return v
+}
+
+// ue reads unsigned exp-golomb code
+func (r *bitReader) ue() uint {
+	zeros := 0
+	for r.bit() == 0 {
+		if r.err || zeros > 31 {
+			r.err = true
+			return 0
+		}
+		zeros++
+	}
+	return 1<<uint(zeros) - 1 + r.bits(zeros)
+}
+
+// se reads signed exp-golomb code
+func (r *bitReader) se() int {
+	v := r.ue()
+	if v&1 == 1 {
+		return int(v+1) / 2
+	}
+	return -int(v / 2)
+}
+
+var errSPS = errors.New("wrong h264 sps")
+
+func parseSPS(sps []byte) (int, int, error) {
+	r := &bitReader{data: sps}
+	profile := r.bits(8)
+	r.bits(16) // constraints and level
+	r.ue()     // sps id
+	chroma := uint(1)
+	switch profile {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		chroma = r.ue()
+		if chroma == 3 {
+			r.bit() // separate colour planes
+		}
+		r.ue()  // luma bit depth
+		r.ue()  // chroma bit depth
+		r.bit() // qpprime y zero transform bypass
+		if r.bit() == 1 {
+			lists := 8
+			if chroma == 3 {
+				lists = 12
+			}
+			for i := 0; i < lists && !r.err; i++ {
+				if r.bit() == 0 {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				last, next := 8, 8
+				for j := 0; j < size && !r.err; j++ {
+					if next != 0 {
+						next = (last + r.se() + 256) % 256
+					}
+					if next != 0 {
+						last = next
+					}
+				}
+			}
+		}
+	}
+	r.ue() // log2 max frame num
+	switch r.ue() {
+	case 0:
+		r.ue() // log2 max pic order cnt lsb
+	case 1:
+		r.bit()
+		r.se()
+		r.se()
+		n := r.ue()
+		for i := uint(0); i < n && !r.err; i++ {
+			r.se()
+		}
+	}
+	r.ue()  // max ref frames
+	r.bit() // gaps in frame num allowed
+	widthMbs := int(r.ue()) + 1
+	heightMaps := int(r.ue()) + 1
+	frameMbsOnly := int(r.bit())
+	if frameMbsOnly == 0 {
+		r.bit() // mb adaptive frame field
+	}
+	r.bit() // direct 8x8 inference
+	var cropLeft, cropRight, cropTop, cropBottom int
+	if r.bit() == 1 {
+		cropLeft, cropRight = int(r.ue()), int(r.ue())
+		cropTop, cropBottom = int(r.ue()), int(r.ue())
+	}
+	if r.err {
+		return 0, 0, errSPS
+	}
+	unitX, unitY := 1, 2-frameMbsOnly
+	switch chroma {
+	case 1:
+		unitX, unitY = 2, 2*(2-frameMbsOnly)
+	case 2:
+		unitX = 2
+	}
+	width := widthMbs*16 - (cropLeft+cropRight)*unitX
+	height := (2-frameMbsOnly)*heightMaps*16 - (cropTop+cropBottom)*unitY
+	return width, height, nil
+}
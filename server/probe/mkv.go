@@ -0,0 +1,325 @@
+package probe
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+)
+
+// Matroska element ids
+const (
+	mkvSegment     = 0x18538067
+	mkvSeekHead    = 0x114D9B74
+	mkvSeek        = 0x4DBB
+	mkvSeekID      = 0x53AB
+	mkvSeekPos     = 0x53AC
+	mkvInfo        = 0x1549A966
+	mkvTimecode    = 0x2AD7B1
+	mkvDuration    = 0x4489
+	mkvTracks      = 0x1654AE6B
+	mkvTrackEntry  = 0xAE
+	mkvTrackType   = 0x83
+	mkvCodecID     = 0x86
+	mkvLanguage    = 0x22B59C
+	mkvLangIETF    = 0x22B59D
+	mkvName        = 0x536E
+	mkvVideo       = 0xE0
+	mkvPixelWidth  = 0xB0
+	mkvPixelHeight = 0xBA
+	mkvAudio       = 0xE1
+	mkvSampleRate  = 0xB5
+	mkvChannels    = 0x9F
+	mkvCluster     = 0x1F43B675
+)
+
+var mkvCodecs = map[string]string{
+	"V_MPEG4/ISO/AVC":  "h264",
+	"V_MPEGH/ISO/HEVC": "hevc",
+	"V_MPEG4/ISO/ASP":  "mpeg4",
+	"V_MPEG2":          "mpeg2video",
+	"V_MPEG1":          "mpeg1video",
+	"V_VP8":            "vp8",
+	"V_VP9":            "vp9",
+	"V_AV1":            "av1",
+	"A_AAC":            "aac",
+	"A_AC3":            "ac3",
+	"A_EAC3":           "eac3",
+	"A_DTS":            "dts",
+	"A_TRUEHD":         "truehd",
+	"A_FLAC":           "flac",
+	"A_OPUS":           "opus",
+	"A_VORBIS":         "vorbis",
+	"A_MPEG/L3":        "mp3",
+	"A_MPEG/L2":        "mp2",
+	"S_TEXT/UTF8":      "subrip",
+	"S_TEXT/ASS":       "ass",
+	"S_TEXT/SSA":       "ass",
+	"S_TEXT/WEBVTT":    "webvtt",
+	"S_HDMV/PGS":       "pgs",
+	"S_VOBSUB":         "dvd_subtitle",
+	"S_DVBSUB":         "dvb_subtitle",
+}
+
+// mkvUnknownSize is size of live streamed element, till end of parent
+const mkvUnknownSize = -1
+
+func parseMKV(s *source) (*Info, error) {
+	// EBML header
+	id, off, size, err := mkvElementAt(s, 0)
+	if err != nil || id != 0x1A45DFA3 || size == mkvUnknownSize {
+		return nil, errors.New("wrong mkv header")
+	}
+	id, segStart, segSize, err := mkvElementAt(s, off+size)
+	if err != nil || id != mkvSegment {
+		return nil, errors.New("mkv segment not found")
+	}
+	segEnd := s.size
+	if segSize != mkvUnknownSize && segStart+segSize < segEnd {
+		segEnd = segStart + segSize
+	}
+
+	info := &Info{Format: "mkv"}
+	var gotInfo, gotTracks bool
+	seeks := make(map[uint64]int64)
+	read := func(id uint64, off, size int64) error {
+		body, err := s.readAt(off, size)
+		if err != nil {
+			return err
+		}
+		switch id {
+		case mkvInfo:
+			parseMKVInfo(info, body)
+			gotInfo = true
+		case mkvTracks:
+			parseMKVTracks(info, body)
+			gotTracks = true
+		case mkvSeekHead:
+			mkvElements(body, func(id uint64, body []byte) {
+				if id != mkvSeek {
+					return
+				}
+				var seekID uint64
+				var pos int64
+				mkvElements(body, func(id uint64, body []byte) {
+					switch id {
+					case mkvSeekID:
+						seekID = mkvUint(body)
+					case mkvSeekPos:
+						pos = int64(mkvUint(body))
+					}
+				})
+				seeks[seekID] = segStart + pos
+			})
+		}
+		return nil
+	}
+
+	pos := segStart
+	for i := 0; pos < segEnd && i < 1000 && !(gotInfo && gotTracks); i++ {
+		id, dataOff, size, err := mkvElementAt(s, pos)
+		if err != nil {
+			break
+		}
+		if id == mkvCluster || size == mkvUnknownSize {
+			// media data, headers are found by seek head
+			break
+		}
+		if id == mkvInfo || id == mkvTracks || id == mkvSeekHead {
+			if err = read(id, dataOff, size); err != nil {
+				return nil, err
+			}
+		}
+		pos = dataOff + size
+	}
+	for _, id := range []uint64{mkvInfo, mkvTracks} {
+		if (id == mkvInfo && gotInfo) || (id == mkvTracks && gotTracks) {
+			continue
+		}
+		if pos, ok := seeks[id]; ok {
+			if eid, dataOff, size, err := mkvElementAt(s, pos); err == nil && eid == id && size != mkvUnknownSize {
+				if err = read(id, dataOff, size); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if !gotTracks {
+		return nil, errors.New("mkv tracks not found")
+	}
+	return info, nil
+}
+
+func parseMKVInfo(info *Info, data []byte) {
+	scale := uint64(1000000)
+	var dur float64
+	mkvElements(data, func(id uint64, body []byte) {
+		switch id {
+		case mkvTimecode:
+			scale = mkvUint(body)
+		case mkvDuration:
+			dur = mkvFloat(body)
+		}
+	})
+	info.Duration = dur * float64(scale) / 1e9
+}
+
+func parseMKVTracks(info *Info, data []byte) {
+	mkvElements(data, func(id uint64, body []byte) {
+		if id != mkvTrackEntry {
+			return
+		}
+		t := &Track{Lang: "eng"}
+		var typ uint64
+		var ietf string
+		mkvElements(body, func(id uint64, body []byte) {
+			switch id {
+			case mkvTrackType:
+				typ = mkvUint(body)
+			case mkvCodecID:
+				t.Codec = mkvCodec(string(body))
+			case mkvLanguage:
+				t.Lang = strings.TrimRight(string(body), "\x00")
+			case mkvLangIETF:
+				ietf = strings.TrimRight(string(body), "\x00")
+			case mkvName:
+				t.Title = strings.TrimRight(string(body), "\x00")
+			case mkvVideo:
+				mkvElements(body, func(id uint64, body []byte) {
+					switch id {
+					case mkvPixelWidth:
+						t.Width = int(mkvUint(body))
+					case mkvPixelHeight:
+						t.Height = int(mkvUint(body))
+					}
+				})
+			case mkvAudio:
+				t.Channels = 1
+				mkvElements(body, func(id uint64, body []byte) {
+					switch id {
+					case mkvSampleRate:
+						t.SampleRate = int(mkvFloat(body))
+					case mkvChannels:
+						t.Channels = int(mkvUint(body))
+					}
+				})
+			}
+		})
+		if ietf != "" {
+			t.Lang = ietf
+		}
+		if t.Lang == "und" {
+			t.Lang = ""
+		}
+		switch typ {
+		case 1:
+			t.Type = TrackVideo
+		case 2:
+			t.Type = TrackAudio
+		case 0x11:
+			t.Type = TrackSubtitle
+		default:
+			return
+		}
+		info.Tracks = append(info.Tracks, t)
+	})
+}
+
+func mkvCodec(id string) string {
+	id = strings.TrimRight(id, "\x00")
+	if codec, ok := mkvCodecs[id]; ok {
+		return codec
+	}
+	// e.g. A_AAC/MPEG4/LC, A_DTS/MA
+	if i := strings.IndexByte(id, '/'); i > 0 {
+		if codec, ok := mkvCodecs[id[:i]]; ok {
+			return codec
+		}
+	}
+	return strings.ToLower(id)
+}
+
+// mkvElementAt reads element header at off, returns id, data offset and size
+func mkvElementAt(s *source, off int64) (uint64, int64, int64, error) {
+	hdr, err := s.readAt(off, 12)
+	if len(hdr) < 2 {
+		if err == nil {
+			err = errors.New("short mkv element")
+		}
+		return 0, 0, 0, err
+	}
+	id, n := mkvVint(hdr, true)
+	if n == 0 {
+		return 0, 0, 0, errors.New("wrong mkv element id")
+	}
+	size, m := mkvVint(hdr[n:], false)
+	if m == 0 {
+		return 0, 0, 0, errors.New("wrong mkv element size")
+	}
+	if size == math.MaxUint64 {
+		return id, off + int64(n+m), mkvUnknownSize, nil
+	}
+	return id, off + int64(n+m), int64(size), nil
+}
+
+// mkvElements calls fn for each element in data
+func mkvElements(data []byte, fn func(id uint64, body []byte)) {
+	for len(data) > 0 {
+		id, n := mkvVint(data, true)
+		if n == 0 {
+			return
+		}
+		size, m := mkvVint(data[n:], false)
+		if m == 0 || size > uint64(len(data)-n-m) {
+			return
+		}
+		fn(id, data[n+m:n+m+int(size)])
+		data = data[n+m+int(size):]
+	}
+}
+
+// mkvVint reads variable length integer, ids keep length marker, all ones
+// size is returned as max uint64
+func mkvVint(data []byte, isID bool) (uint64, int) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0
+	}
+	n := 1
+	for mask := byte(0x80); data[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > 8 || len(data) < n {
+		return 0, 0
+	}
+	v := uint64(data[0])
+	if !isID {
+		v &= uint64(0xFF >> n)
+	}
+	allOnes := v == uint64(0xFF>>n)
+	for _, b := range data[1:n] {
+		v = v<<8 | uint64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+	if !isID && allOnes {
+		return math.MaxUint64, n
+	}
+	return v, n
+}
+
+func mkvUint(data []byte) uint64 {
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+func mkvFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
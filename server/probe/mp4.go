@@ -0,0 +1,213 @@
+package probe
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var mp4Codecs = map[string]string{
+	"avc1": "h264",
+	"avc3": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"av01": "av1",
+	"vp09": "vp9",
+	"mp4v": "mpeg4",
+	"mp4a": "aac",
+	"ac-3": "ac3",
+	"ec-3": "eac3",
+	"Opus": "opus",
+	"fLaC": "flac",
+	".mp3": "mp3",
+	"tx3g": "mov_text",
+	"wvtt": "webvtt",
+	"stpp": "ttml",
+	"c608": "eia_608",
+}
+
+var mp4Handlers = map[string]string{
+	"vide": TrackVideo,
+	"soun": TrackAudio,
+	"sbtl": TrackSubtitle,
+	"subt": TrackSubtitle,
+	"text": TrackSubtitle,
+	"clcp": TrackSubtitle,
+}
+
+func parseMP4(s *source) (*Info, error) {
+	var off int64
+	for off+8 <= s.size {
+		hdr, err := s.readAt(off, 16)
+		if len(hdr) < 8 {
+			return nil, shortErr(err)
+		}
+		size := int64(binary.BigEndian.Uint32(hdr))
+		hl := int64(8)
+		switch size {
+		case 0:
+			size = s.size - off
+		case 1:
+			if len(hdr) < 16 {
+				return nil, errors.New("wrong mp4 box")
+			}
+			size = int64(binary.BigEndian.Uint64(hdr[8:]))
+			hl = 16
+		}
+		if size < hl {
+			return nil, errors.New("wrong mp4 box")
+		}
+		if string(hdr[4:8]) == "moov" {
+			body, err := s.readAt(off+hl, size-hl)
+			if err != nil {
+				return nil, err
+			}
+			return parseMoov(body), nil
+		}
+		off += size
+	}
+	return nil, errors.New("mp4 moov not found")
+}
+
+// mp4Boxes calls fn for each box in data
+func mp4Boxes(data []byte, fn func(typ string, body []byte)) {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data))
+		hl := uint64(8)
+		if size == 1 && len(data) >= 16 {
+			size = binary.BigEndian.Uint64(data[8:])
+			hl = 16
+		} else if size == 0 {
+			size = uint64(len(data))
+		}
+		if size < hl || size > uint64(len(data)) {
+			return
+		}
+		fn(string(data[4:8]), data[hl:size])
+		data = data[size:]
+	}
+}
+
+func parseMoov(moov []byte) *Info {
+	info := &Info{Format: "mp4"}
+	var timescale uint32
+	mp4Boxes(moov, func(typ string, body []byte) {
+		switch typ {
+		case "mvhd":
+			var dur uint64
+			if len(body) >= 32 && body[0] == 1 {
+				timescale = binary.BigEndian.Uint32(body[20:])
+				dur = binary.BigEndian.Uint64(body[24:])
+			} else if len(body) >= 20 {
+				timescale = binary.BigEndian.Uint32(body[12:])
+				dur = uint64(binary.BigEndian.Uint32(body[16:]))
+			}
+			if timescale > 0 && dur != 0 && dur != 0xFFFFFFFF && dur != 0xFFFFFFFFFFFFFFFF {
+				info.Duration = float64(dur) / float64(timescale)
+			}
+		case "mvex":
+			// fragmented mp4 keeps duration in movie extends header
+			mp4Boxes(body, func(typ string, body []byte) {
+				if typ != "mehd" || timescale == 0 || info.Duration > 0 {
+					return
+				}
+				if len(body) >= 12 && body[0] == 1 {
+					info.Duration = float64(binary.BigEndian.Uint64(body[4:])) / float64(timescale)
+				} else if len(body) >= 8 {
+					info.Duration = float64(binary.BigEndian.Uint32(body[4:])) / float64(timescale)
+				}
+			})
+		case "trak":
+			if t := parseTrak(body); t != nil {
+				info.Tracks = append(info.Tracks, t)
+			}
+		}
+	})
+	return info
+}
+
+func parseTrak(trak []byte) *Track {
+	t := &Track{}
+	var width, height int
+	mp4Boxes(trak, func(typ string, body []byte) {
+		switch typ {
+		case "tkhd":
+			if len(body) >= 8 {
+				// fixed point 16.16 at the end
+				width = int(binary.BigEndian.Uint32(body[len(body)-8:]) >> 16)
+				height = int(binary.BigEndian.Uint32(body[len(body)-4:]) >> 16)
+			}
+		case "mdia":
+			mp4Boxes(body, func(typ string, body []byte) {
+				switch typ {
+				case "mdhd":
+					pos := 20
+					if len(body) > 0 && body[0] == 1 {
+						pos = 32
+					}
+					if len(body) >= pos+2 {
+						t.Lang = mp4Lang(binary.BigEndian.Uint16(body[pos:]))
+					}
+				case "hdlr":
+					if len(body) >= 12 {
+						t.Type = mp4Handlers[string(body[8:12])]
+					}
+				case "minf":
+					mp4Boxes(body, func(typ string, body []byte) {
+						if typ == "stbl" {
+							mp4Boxes(body, func(typ string, body []byte) {
+								if typ == "stsd" {
+									parseStsd(t, body)
+								}
+							})
+						}
+					})
+				}
+			})
+		}
+	})
+	if t.Type == "" {
+		return nil
+	}
+	if t.Type == TrackVideo && t.Width == 0 {
+		t.Width, t.Height = width, height
+	}
+	return t
+}
+
+// parseStsd reads codec and first sample entry of track
+func parseStsd(t *Track, stsd []byte) {
+	if len(stsd) < 16 {
+		return
+	}
+	entry := stsd[8:]
+	format := string(entry[4:8])
+	if codec, ok := mp4Codecs[format]; ok {
+		t.Codec = codec
+	} else {
+		t.Codec = format
+	}
+	switch t.Type {
+	case TrackVideo:
+		if len(entry) >= 36 {
+			t.Width = int(binary.BigEndian.Uint16(entry[32:]))
+			t.Height = int(binary.BigEndian.Uint16(entry[34:]))
+		}
+	case TrackAudio:
+		if len(entry) >= 36 {
+			t.Channels = int(binary.BigEndian.Uint16(entry[24:]))
+			t.SampleRate = int(binary.BigEndian.Uint16(entry[32:]))
+		}
+	}
+}
+
+// mp4Lang unpacks ISO-639-2/T code, three 5 bit letters
+func mp4Lang(v uint16) string {
+	if v == 0 || v == 0x7FFF {
+		return ""
+	}
+	lang := string([]byte{byte(v>>10&0x1F) + 0x60, byte(v>>5&0x1F) + 0x60, byte(v&0x1F) + 0x60})
+	if lang == "und" {
+		return ""
+	}
+	return lang
+}
@@ -0,0 +1,115 @@
+package probe
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Track types
+const (
+	TrackVideo    = "video"
+	TrackAudio    = "audio"
+	TrackSubtitle = "subtitle"
+)
+
+// Track is stream of media container
+type Track struct {
+	Type       string `json:"type"`
+	Codec      string `json:"codec,omitempty"`
+	Lang       string `json:"lang,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	Channels   int    `json:"channels,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+}
+
+// Info of media file, read from container headers only
+type Info struct {
+	Format   string   `json:"format"`
+	Duration float64  `json:"duration,omitempty"` // seconds
+	Bitrate  int64    `json:"bitrate,omitempty"`  // bit/s
+	Width    int      `json:"width,omitempty"`
+	Height   int      `json:"height,omitempty"`
+	Tracks   []*Track `json:"tracks,omitempty"`
+}
+
+var ErrUnknown = errors.New("unknown media format")
+
+// maxBox limits header read into memory, e.g. mp4 moov
+const maxBox = 64 << 20
+
+// Probe detects container by header and reads its info, size is file length
+func Probe(r io.ReadSeeker, size int64) (*Info, error) {
+	s := &source{r: r, size: size}
+	head, _ := s.readAt(0, 400)
+	if len(head) < 12 {
+		return nil, ErrUnknown
+	}
+	var info *Info
+	var err error
+	switch {
+	case string(head[4:8]) == "ftyp" || string(head[4:8]) == "moov":
+		info, err = parseMP4(s)
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		info, err = parseMKV(s)
+	case string(head[:4]) == "RIFF" && string(head[8:12]) == "AVI ":
+		info, err = parseAVI(s)
+	case tsPacketSize(head) > 0:
+		info, err = parseTS(s, tsPacketSize(head))
+	default:
+		return nil, ErrUnknown
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, ErrUnknown
+	}
+	if info.Bitrate == 0 && info.Duration > 0 {
+		info.Bitrate = int64(float64(size) * 8 / info.Duration)
+	}
+	for _, t := range info.Tracks {
+		if t.Type == TrackVideo && t.Width > 0 {
+			info.Width, info.Height = t.Width, t.Height
+			break
+		}
+	}
+	return info, nil
+}
+
+type source struct {
+	r    io.ReadSeeker
+	size int64
+}
+
+// errShort is returned when header is cut by end of file
+var errShort = errors.New("media header is cut")
+
+// shortErr returns error of short read, readAt has no error at end of file
+func shortErr(err error) error {
+	if err == nil {
+		return errShort
+	}
+	return err
+}
+
+// readAt reads up to n bytes at off, less at end of file
+func (s *source) readAt(off int64, n int64) ([]byte, error) {
+	if off >= s.size {
+		return nil, io.EOF
+	}
+	if off+n > s.size {
+		n = s.size - off
+	}
+	if n > maxBox {
+		return nil, errors.New("header is too big")
+	}
+	if _, err := s.r.Seek(off, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	m, err := io.ReadFull(s.r, buf)
+	return buf[:m], err
+}
@@ -0,0 +1,119 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// ebml builds matroska element with one byte size
+func ebml(id uint64, body ...[]byte) []byte {
+	data := bytes.Join(body, nil)
+	var buf []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> uint(shift)); b != 0 || len(buf) > 0 {
+			buf = append(buf, b)
+		}
+	}
+	buf = append(buf, 0x80|byte(len(data)))
+	return append(buf, data...)
+}
+
+func testMKV() []byte {
+	dur := make([]byte, 8)
+	binary.BigEndian.PutUint64(dur, math.Float64bits(5000))
+	return bytes.Join([][]byte{
+		ebml(0x1A45DFA3, ebml(0x4282, []byte("matroska"))),
+		ebml(mkvSegment,
+			ebml(mkvInfo, ebml(mkvTimecode, []byte{0x0F, 0x42, 0x40}), ebml(mkvDuration, dur)),
+			ebml(mkvTracks,
+				ebml(mkvTrackEntry, ebml(mkvTrackType, []byte{1}), ebml(mkvCodecID, []byte("V_MPEG4/ISO/AVC")),
+					ebml(mkvVideo, ebml(mkvPixelWidth, []byte{0x07, 0x80}), ebml(mkvPixelHeight, []byte{0x04, 0x38}))),
+				ebml(mkvTrackEntry, ebml(mkvTrackType, []byte{2}), ebml(mkvCodecID, []byte("A_AC3")),
+					ebml(mkvLanguage, []byte("rus")), ebml(mkvAudio, ebml(mkvChannels, []byte{6})))),
+		),
+	}, nil)
+}
+
+func box(typ string, body ...[]byte) []byte {
+	data := bytes.Join(body, nil)
+	buf := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(8+len(data)))
+	copy(buf[4:], typ)
+	return append(buf, data...)
+}
+
+func testMP4() []byte {
+	mvhd := make([]byte, 20)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], 90000)
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[76:], 1280<<16)
+	binary.BigEndian.PutUint32(tkhd[80:], 720<<16)
+	hdlr := make([]byte, 24)
+	copy(hdlr[8:], "vide")
+	stsd := make([]byte, 8, 44)
+	entry := make([]byte, 36)
+	copy(entry[4:], "avc1")
+	stsd = append(stsd, entry...)
+	return bytes.Join([][]byte{
+		box("ftyp", []byte("isom\x00\x00\x02\x00")),
+		box("moov", box("mvhd", mvhd), box("trak", box("tkhd", tkhd),
+			box("mdia", box("hdlr", hdlr), box("minf", box("stbl", box("stsd", stsd)))))),
+	}, nil)
+}
+
+func TestProbeMKV(t *testing.T) {
+	data := testMKV()
+	info, err := Probe(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != "mkv" || info.Duration != 5 || info.Width != 1920 || info.Height != 1080 {
+		t.Fatalf("wrong info %+v", info)
+	}
+	if len(info.Tracks) != 2 || info.Tracks[1].Codec != "ac3" || info.Tracks[1].Lang != "rus" || info.Tracks[1].Channels != 6 {
+		t.Fatalf("wrong tracks %+v %+v", info.Tracks[0], info.Tracks[len(info.Tracks)-1])
+	}
+}
+
+func TestProbeMP4(t *testing.T) {
+	data := testMP4()
+	info, err := Probe(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != "mp4" || info.Duration != 90 || info.Width != 1280 || info.Height != 720 {
+		t.Fatalf("wrong info %+v", info)
+	}
+	if len(info.Tracks) != 1 || info.Tracks[0].Codec != "h264" {
+		t.Fatalf("wrong tracks %+v", info.Tracks)
+	}
+}
+
+func TestProbeShort(t *testing.T) {
+	heads := [][]byte{
+		testMKV(),
+		testMP4(),
+		[]byte("RIFF\x00\x10\x00\x00AVI LIST\x00\x10\x00\x00hdrl"),
+		bytes.Repeat([]byte{0x47, 0, 0, 0x10}, 200),
+	}
+	for _, head := range heads {
+		for n := 0; n < len(head); n++ {
+			if _, err := Probe(bytes.NewReader(head[:n]), int64(n)); err == nil && n < 12 {
+				t.Fatalf("no error for %d bytes", n)
+			}
+		}
+	}
+}
+
+func FuzzProbe(f *testing.F) {
+	f.Add(testMKV())
+	f.Add(testMP4())
+	f.Add([]byte("RIFF\x00\x10\x00\x00AVI LIST\x00\x10\x00\x00hdrl"))
+	f.Add(bytes.Repeat([]byte{0x47, 0x40, 0, 0x10}, 100))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		Probe(bytes.NewReader(data), int64(len(data)))
+	})
+}
@@ -0,0 +1,252 @@
+package probe
+
+import (
+	"bytes"
+	"errors"
+)
+
+// tsScan is data read at start and end of transport stream
+const tsScan = 2 << 20
+
+var tsStreamTypes = map[byte]*Track{
+	0x01: {Type: TrackVideo, Codec: "mpeg1video"},
+	0x02: {Type: TrackVideo, Codec: "mpeg2video"},
+	0x03: {Type: TrackAudio, Codec: "mp2"},
+	0x04: {Type: TrackAudio, Codec: "mp2"},
+	0x0F: {Type: TrackAudio, Codec: "aac"},
+	0x10: {Type: TrackVideo, Codec: "mpeg4"},
+	0x11: {Type: TrackAudio, Codec: "aac_latm"},
+	0x1B: {Type: TrackVideo, Codec: "h264"},
+	0x24: {Type: TrackVideo, Codec: "hevc"},
+	0x80: {Type: TrackAudio, Codec: "pcm_bluray"},
+	0x81: {Type: TrackAudio, Codec: "ac3"},
+	0x82: {Type: TrackAudio, Codec: "dts"},
+	0x83: {Type: TrackAudio, Codec: "truehd"},
+	0x84: {Type: TrackAudio, Codec: "eac3"},
+	0x85: {Type: TrackAudio, Codec: "dts"},
+	0x86: {Type: TrackAudio, Codec: "dts"},
+	0x87: {Type: TrackAudio, Codec: "eac3"},
+	0x90: {Type: TrackSubtitle, Codec: "pgs"},
+	0x92: {Type: TrackSubtitle, Codec: "hdmv_text_subtitle"},
+}
+
+// tsPacketSize returns 188 for MPEG-TS, 192 for M2TS or 0
+func tsPacketSize(head []byte) int {
+	for _, size := range []int{188, 192} {
+		start := size - 188
+		if len(head) > start+2*size && head[start] == 0x47 && head[start+size] == 0x47 && head[start+2*size] == 0x47 {
+			return size
+		}
+	}
+	return 0
+}
+
+type tsParser struct {
+	packetSize int
+	pmtPID     int
+	pcrPID     int
+	tracks     map[int]*Track
+	order      []int
+	firstPCR   int64
+	lastPCR    int64
+	h264PID    int
+	h264Data   []byte
+}
+
+func parseTS(s *source, packetSize int) (*Info, error) {
+	p := &tsParser{packetSize: packetSize, pmtPID: -1, pcrPID: -1, h264PID: -1, firstPCR: -1, lastPCR: -1}
+	head, err := s.readAt(0, tsScan)
+	if len(head) == 0 {
+		return nil, shortErr(err)
+	}
+	p.scan(head)
+	if p.tracks == nil {
+		return nil, errors.New("ts program map not found")
+	}
+	if s.size > tsScan {
+		// last pcr is at the end of file
+		off := s.size - tsScan
+		off -= off % int64(packetSize)
+		if tail, _ := s.readAt(off, s.size-off); len(tail) > 0 {
+			p.lastPCR = -1
+			p.scan(tail)
+		}
+	}
+
+	info := &Info{Format: "ts"}
+	if p.firstPCR >= 0 && p.lastPCR >= 0 {
+		diff := p.lastPCR - p.firstPCR
+		if diff < 0 {
+			// 33 bit base wraps around
+			diff += (1 << 33) * 300
+		}
+		info.Duration = float64(diff) / 27e6
+	}
+	for _, pid := range p.order {
+		info.Tracks = append(info.Tracks, p.tracks[pid])
+	}
+	return info, nil
+}
+
+func (p *tsParser) scan(data []byte) {
+	for off := p.packetSize - 188; off+188 <= len(data); off += p.packetSize {
+		pkt := data[off : off+188]
+		if pkt[0] != 0x47 {
+			continue
+		}
+		pusi := pkt[1]&0x40 != 0
+		pid := int(pkt[1]&0x1F)<<8 | int(pkt[2])
+		afc := pkt[3] >> 4 & 3
+		payload := pkt[4:]
+		if afc&2 != 0 {
+			alen := int(pkt[4])
+			if alen > 183 {
+				continue
+			}
+			if alen >= 7 && pkt[5]&0x10 != 0 && (pid == p.pcrPID || p.pcrPID < 0) {
+				a := pkt[6:]
+				base := int64(a[0])<<25 | int64(a[1])<<17 | int64(a[2])<<9 | int64(a[3])<<1 | int64(a[4])>>7
+				ext := int64(a[4]&1)<<8 | int64(a[5])
+				pcr := base*300 + ext
+				if p.firstPCR < 0 {
+					p.firstPCR = pcr
+				}
+				p.lastPCR = pcr
+			}
+			payload = pkt[5+alen:]
+		}
+		if afc&1 == 0 {
+			continue
+		}
+		switch {
+		case pid == 0 && pusi && p.pmtPID < 0:
+			p.parsePAT(tsSection(payload))
+		case pid == p.pmtPID && pusi && p.tracks == nil:
+			p.parsePMT(tsSection(payload))
+		case pid == p.h264PID:
+			p.h264Packet(payload, pusi)
+		}
+	}
+}
+
+// tsSection skips pointer field of section payload
+func tsSection(payload []byte) []byte {
+	if len(payload) == 0 || int(payload[0])+1 > len(payload) {
+		return nil
+	}
+	return payload[1+int(payload[0]):]
+}
+
+func (p *tsParser) parsePAT(sec []byte) {
+	if len(sec) < 8 || sec[0] != 0 {
+		return
+	}
+	end := 3 + (int(sec[1]&0x0F)<<8 | int(sec[2])) - 4
+	if end > len(sec) {
+		end = len(sec)
+	}
+	for i := 8; i+4 <= end; i += 4 {
+		program := int(sec[i])<<8 | int(sec[i+1])
+		if program != 0 {
+			p.pmtPID = int(sec[i+2]&0x1F)<<8 | int(sec[i+3])
+			return
+		}
+	}
+}
+
+func (p *tsParser) parsePMT(sec []byte) {
+	if len(sec) < 12 || sec[0] != 2 {
+		return
+	}
+	end := 3 + (int(sec[1]&0x0F)<<8 | int(sec[2])) - 4
+	if end > len(sec) {
+		end = len(sec)
+	}
+	p.pcrPID = int(sec[8]&0x1F)<<8 | int(sec[9])
+	p.firstPCR, p.lastPCR = -1, -1
+	p.tracks = make(map[int]*Track)
+	i := 12 + (int(sec[10]&0x0F)<<8 | int(sec[11]))
+	for i+5 <= end {
+		typ := sec[i]
+		pid := int(sec[i+1]&0x1F)<<8 | int(sec[i+2])
+		esLen := int(sec[i+3]&0x0F)<<8 | int(sec[i+4])
+		if i+5+esLen > end {
+			break
+		}
+		var t *Track
+		if known, ok := tsStreamTypes[typ]; ok {
+			t = &Track{Type: known.Type, Codec: known.Codec}
+		}
+		t = tsDescriptors(t, sec[i+5:i+5+esLen])
+		if t != nil {
+			p.tracks[pid] = t
+			p.order = append(p.order, pid)
+			if t.Codec == "h264" && p.h264PID < 0 {
+				p.h264PID = pid
+			}
+		}
+		i += 5 + esLen
+	}
+}
+
+// tsDescriptors reads language and codec of private streams
+func tsDescriptors(t *Track, data []byte) *Track {
+	for len(data) >= 2 {
+		tag, size := data[0], int(data[1])
+		if 2+size > len(data) {
+			break
+		}
+		body := data[2 : 2+size]
+		switch tag {
+		case 0x6A:
+			t = tsTrack(t, TrackAudio, "ac3")
+		case 0x7A:
+			t = tsTrack(t, TrackAudio, "eac3")
+		case 0x7B:
+			t = tsTrack(t, TrackAudio, "dts")
+		case 0x59:
+			t = tsTrack(t, TrackSubtitle, "dvb_subtitle")
+		case 0x56:
+			t = tsTrack(t, TrackSubtitle, "dvb_teletext")
+		}
+		if t != nil && t.Lang == "" && size >= 3 && (tag == 0x0A || tag == 0x59 || tag == 0x56) {
+			if lang := string(bytes.TrimRight(body[:3], "\x00 ")); lang != "und" {
+				t.Lang = lang
+			}
+		}
+		data = data[2+size:]
+	}
+	return t
+}
+
+func tsTrack(t *Track, typ, codec string) *Track {
+	if t == nil {
+		t = &Track{Type: typ}
+	}
+	t.Codec = codec
+	return t
+}
+
+// h264Packet collects start of video stream to find sequence parameter set
+func (p *tsParser) h264Packet(payload []byte, pusi bool) {
+	if pusi && len(payload) >= 9 && payload[0] == 0 && payload[1] == 0 && payload[2] == 1 {
+		// skip PES header
+		hl := 9 + int(payload[8])
+		if hl > len(payload) {
+			return
+		}
+		payload = payload[hl:]
+	} else if p.h264Data == nil {
+		return
+	}
+	p.h264Data = append(p.h264Data, payload...)
+	if w, h, ok := h264Size(p.h264Data); ok {
+		t := p.tracks[p.h264PID]
+		t.Width, t.Height = w, h
+		p.h264PID = -1
+		p.h264Data = nil
+	} else if len(p.h264Data) > 1<<20 {
+		p.h264PID = -1
+		p.h264Data = nil
+	}
+}
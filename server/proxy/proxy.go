@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Traffic classes, each may have own proxy
+const (
+	Trackers = "trackers" // tracker announces and web seeds of torrent client
+	Peers    = "peers"    // peer connections
+	HTTP     = "http"     // server requests: torrent links, posters, tracker lists
+)
+
+// Direct in class setting disables default proxy for the class
+const Direct = "direct"
+
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+var (
+	proxies = make(map[string]string)
+	mu      sync.RWMutex
+)
+
+// Set configures proxy urls, def is used for classes with empty url
+func Set(def, trackers, peers, http string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for class, link := range map[string]string{Trackers: trackers, Peers: peers, HTTP: http} {
+		if link == "" {
+			link = def
+		}
+		proxies[class] = link
+	}
+}
+
+// URL returns proxy of traffic class, nil if connect directly
+func URL(class string) *url.URL {
+	mu.RLock()
+	link := proxies[class]
+	mu.RUnlock()
+	if link == "" || link == Direct {
+		return nil
+	}
+	u, err := Parse(link)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// Parse checks proxy url, supported socks5, socks5h and http
+func Parse(link string) (*url.URL, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, errors.New("unsupported proxy scheme: " + u.Scheme)
+	}
+	if u.Host == "" || u.Port() == "" {
+		return nil, errors.New("proxy host and port required: " + link)
+	}
+	return u, nil
+}
+
+// Check validates proxy urls
+func Check(links ...string) error {
+	for _, link := range links {
+		if link == "" || link == Direct {
+			continue
+		}
+		if _, err := Parse(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Func returns proxy func for http.Transport, nil if direct
+func Func(class string) func(*http.Request) (*url.URL, error) {
+	u := URL(class)
+	if u == nil {
+		return nil
+	}
+	return http.ProxyURL(u)
+}
+
+// Dialer returns tcp dialer of traffic class, nil if direct
+func Dialer(class string) ContextDialer {
+	u := URL(class)
+	if u == nil {
+		return nil
+	}
+	direct := &net.Dialer{Timeout: 30 * time.Second}
+	if u.Scheme == "http" {
+		return &connectDialer{proxy: u, dialer: direct}
+	}
+	d, err := proxy.FromURL(u, direct)
+	if err != nil {
+		return nil
+	}
+	if cd, ok := d.(ContextDialer); ok {
+		return cd
+	}
+	return nil
+}
+
+// HTTPClient returns client for outgoing requests of traffic class
+func HTTPClient(class string, timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if fn := Func(class); fn != nil {
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.Proxy = fn
+		client.Transport = tr
+	}
+	return client
+}
+
+// connectDialer tunnels connections through http proxy with CONNECT
+type connectDialer struct {
+	proxy  *url.URL
+	dialer *net.Dialer
+}
+
+func (d *connectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, "tcp", d.proxy.Host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	req := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+	if d.proxy.User != nil {
+		pass, _ := d.proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(d.proxy.User.Username() + ":" + pass))
+		req += "Proxy-Authorization: Basic " + auth + "\r\n"
+	}
+	req += "\r\n"
+	if _, err = conn.Write([]byte(req)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, errors.New("proxy connect: " + resp.Status)
+	}
+	if br.Buffered() > 0 {
+		conn.Close()
+		return nil, errors.New("proxy connect: unexpected data")
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"io/ioutil"
 	"net"
 	"os"
@@ -8,6 +9,7 @@ import (
 
 	"server/log"
 	"server/settings"
+	"server/utils"
 	"server/web"
 )
 
@@ -16,18 +18,28 @@ func Start(port string, roSets bool) {
 	if port == "" {
 		port = "8090"
 	}
-	log.TLogln("Check web port", port)
-	l, err := net.Listen("tcp", ":"+port)
+	bind := settings.WebBind
+	if bind == "" {
+		bind = settings.BTsets.WebBind
+	}
+	host, err := utils.ResolveBind(bind)
+	if err != nil {
+		log.TLogln("Error web server", err, "Abort")
+		os.Exit(1)
+	}
+	addr := net.JoinHostPort(host, port)
+	log.TLogln("Check web port", addr)
+	l, err := net.Listen("tcp", addr)
 	if l != nil {
 		l.Close()
 	}
 	if err != nil {
-		log.TLogln("Port", port, "already in use! Abort")
+		log.TLogln("Port", addr, "already in use! Abort")
 		os.Exit(1)
 	} else {
 		go cleanCache()
 		settings.Port = port
-		web.Start(port)
+		web.Start(host, port)
 	}
 }
 
@@ -87,7 +99,14 @@ func WaitServer() string {
 	return ""
 }
 
-func Stop() {
-	web.Stop()
-	settings.CloseDB()
+// Stop shuts down web server, streams and torrents within ctx, closes db
+// and returns exit code
+func Stop(ctx context.Context) int {
+	web.Stop(ctx)
+	if err := settings.CloseDB(); err != nil {
+		log.TLogln("Error close db:", err)
+		return 1
+	}
+	log.TLogln("Server stopped")
+	return 0
 }
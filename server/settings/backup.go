@@ -0,0 +1,297 @@
+package settings
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"server/log"
+	"server/version"
+)
+
+const BackupVersion = 1
+
+const (
+	RestoreMerge   = "merge"
+	RestoreReplace = "replace"
+)
+
+type BackupManifest struct {
+	Version int    `json:"version"`
+	Created int64  `json:"created"`
+	Server  string `json:"server"`
+}
+
+type Backup struct {
+	Manifest BackupManifest
+	Settings *BTSets
+	Torrents []*TorrentDB
+	Viewed   []*Viewed
+	Accounts map[string]string
+}
+
+type RestoreConflict struct {
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
+type RestoreReport struct {
+	Mode      string             `json:"mode"`
+	DryRun    bool               `json:"dry_run"`
+	Version   int                `json:"version"`
+	Settings  bool               `json:"settings"`
+	Torrents  int                `json:"torrents"`
+	Viewed    int                `json:"viewed"`
+	Accounts  int                `json:"accounts"`
+	Removed   int                `json:"removed"`
+	Conflicts []*RestoreConflict `json:"conflicts"`
+}
+
+func WriteBackup(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	manifest := BackupManifest{
+		Version: BackupVersion,
+		Created: time.Now().Unix(),
+		Server:  version.Version,
+	}
+	entries := []struct {
+		name string
+		val  interface{}
+	}{
+		{"manifest.json", manifest},
+		{"settings.json", BTsets},
+		{"torrents.json", ListTorrent()},
+		{"viewed.json", ListAllViewed("")},
+		{"accounts.json", readAccounts()},
+	}
+	for _, e := range entries {
+		buf, err := json.MarshalIndent(e.val, "", " ")
+		if err != nil {
+			return err
+		}
+		f, err := zw.Create(e.name)
+		if err != nil {
+			return err
+		}
+		if _, err = f.Write(buf); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func ReadBackup(r io.ReaderAt, size int64) (*Backup, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	bk := new(Backup)
+	found := false
+	for _, f := range zr.File {
+		var dst interface{}
+		switch f.Name {
+		case "manifest.json":
+			dst = &bk.Manifest
+			found = true
+		case "settings.json":
+			dst = &bk.Settings
+		case "torrents.json":
+			dst = &bk.Torrents
+		case "viewed.json":
+			dst = &bk.Viewed
+		case "accounts.json":
+			dst = &bk.Accounts
+		default:
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		err = json.NewDecoder(rc).Decode(dst)
+		rc.Close()
+		if err != nil {
+			return nil, errors.New("error read " + f.Name + ": " + err.Error())
+		}
+	}
+	if !found {
+		return nil, errors.New("manifest not found, not a backup archive")
+	}
+	if bk.Manifest.Version < 1 || bk.Manifest.Version > BackupVersion {
+		return nil, errors.New("unsupported backup version")
+	}
+	return bk, nil
+}
+
+// RestoreBackup imports the archive into the db. In merge mode local items win
+// and differing ones are reported as conflicts, in replace mode the archive
+// overwrites everything. With dryRun nothing is written.
+func RestoreBackup(bk *Backup, mode string, dryRun bool) (*RestoreReport, error) {
+	if mode == "" {
+		mode = RestoreMerge
+	}
+	if mode != RestoreMerge && mode != RestoreReplace {
+		return nil, errors.New("unknown restore mode: " + mode)
+	}
+	if ReadOnly && !dryRun {
+		return nil, errors.New("db in read-only mode")
+	}
+
+	rep := &RestoreReport{
+		Mode:      mode,
+		DryRun:    dryRun,
+		Version:   bk.Manifest.Version,
+		Conflicts: []*RestoreConflict{},
+	}
+	conflict := func(kind, key, reason string) {
+		rep.Conflicts = append(rep.Conflicts, &RestoreConflict{kind, key, reason})
+	}
+
+	// Settings
+	if bk.Settings != nil {
+		if mode == RestoreReplace {
+			rep.Settings = true
+			if !dryRun {
+				SetBTSets(bk.Settings)
+			}
+		} else if BTsets != nil && BTsets.String() != bk.Settings.String() {
+			conflict("settings", "BitTorr", "local settings differ, kept")
+		}
+	}
+
+	// Torrents
+	local := make(map[string]*TorrentDB)
+	for _, t := range ListTorrent() {
+		local[t.InfoHash.HexString()] = t
+	}
+	archived := make(map[string]struct{})
+	for _, t := range bk.Torrents {
+		if t == nil || t.TorrentSpec == nil {
+			continue
+		}
+		hash := t.InfoHash.HexString()
+		archived[hash] = struct{}{}
+		if lt, ok := local[hash]; ok && mode == RestoreMerge {
+			if lt.Title != t.Title || lt.Poster != t.Poster || lt.Data != t.Data {
+				conflict("torrent", hash, "local torrent differs, kept")
+			}
+			continue
+		}
+		rep.Torrents++
+		if !dryRun {
+			AddTorrent(t)
+		}
+	}
+	if mode == RestoreReplace {
+		for hash, t := range local {
+			if _, ok := archived[hash]; !ok {
+				rep.Removed++
+				if !dryRun {
+					RemTorrent(t.InfoHash)
+				}
+			}
+		}
+	}
+
+	// Viewed
+	if mode == RestoreReplace && !dryRun {
+		for _, v := range ListAllViewed("") {
+			RemViewed(&Viewed{User: v.User, Hash: v.Hash, FileIndex: -1})
+		}
+	}
+	for _, v := range bk.Viewed {
+		if v == nil || v.Hash == "" {
+			continue
+		}
+		rep.Viewed++
+		if !dryRun {
+			SetViewed(v)
+		}
+	}
+
+	// Accounts
+	if bk.Accounts != nil {
+		accs := readAccounts()
+		if mode == RestoreReplace {
+			accs = make(map[string]string)
+		}
+		for user, pass := range bk.Accounts {
+			if lpass, ok := accs[user]; ok {
+				if lpass != pass {
+					conflict("account", user, "local password differs, kept")
+				}
+				continue
+			}
+			accs[user] = pass
+			rep.Accounts++
+		}
+		if !dryRun && (rep.Accounts > 0 || mode == RestoreReplace) {
+			if err := writeAccounts(accs); err != nil {
+				return rep, err
+			}
+		}
+		if !dryRun && mode == RestoreReplace && len(accs) == 0 {
+			os.Remove(filepath.Join(Path, "accs.db"))
+		}
+	}
+
+	if !dryRun {
+		log.TLogln("Restore backup:", mode, "torrents:", rep.Torrents, "viewed:", rep.Viewed, "accounts:", rep.Accounts, "removed:", rep.Removed)
+	}
+	return rep, nil
+}
+
+func RestoreBackupFile(path, mode string, dryRun bool) (*RestoreReport, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	bk, err := ReadBackup(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		return nil, err
+	}
+	return RestoreBackup(bk, mode, dryRun)
+}
+
+func WriteBackupFile(path string) error {
+	ff, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteBackup(ff)
+	if cerr := ff.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func readAccounts() map[string]string {
+	accs := make(map[string]string)
+	buf, err := ioutil.ReadFile(filepath.Join(Path, "accs.db"))
+	if err != nil {
+		return accs
+	}
+	err = json.Unmarshal(buf, &accs)
+	if err != nil {
+		log.TLogln("Error parse accs.db", err)
+	}
+	return accs
+}
+
+func writeAccounts(accs map[string]string) error {
+	if len(accs) == 0 {
+		return nil
+	}
+	buf, err := json.MarshalIndent(accs, "", " ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(Path, "accs.db"), buf, 0600)
+}
@@ -1,6 +1,7 @@
 package settings
 
 import (
+	"errors"
 	"path/filepath"
 	"strings"
 	"time"
@@ -10,6 +11,8 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
+var ErrReadOnly = errors.New("db in read-only mode")
+
 type TDB struct {
 	Path string
 	db   *bolt.DB
@@ -28,11 +31,21 @@ func NewTDB() *TDB {
 	return tdb
 }
 
-func (v *TDB) CloseDB() {
+// CloseDB waits for running transactions and closes db, later calls
+// return bolt.ErrDatabaseNotOpen
+func (v *TDB) CloseDB() error {
 	if v.db != nil {
-		v.db.Close()
-		v.db = nil
+		return v.db.Close()
 	}
+	return nil
+}
+
+// IsOpen checks that db is not closed
+func (v *TDB) IsOpen() bool {
+	if v.db == nil {
+		return false
+	}
+	return v.db.View(func(tx *bolt.Tx) error { return nil }) == nil
 }
 
 func (v *TDB) Get(xpath, name string) []byte {
@@ -175,3 +188,14 @@ func (v *TDB) Rem(xpath, name string) {
 	}
 
 }
+
+func (v *TDB) View(fn func(tx *bolt.Tx) error) error {
+	return v.db.View(fn)
+}
+
+func (v *TDB) Update(fn func(tx *bolt.Tx) error) error {
+	if ReadOnly {
+		return ErrReadOnly
+	}
+	return v.db.Update(fn)
+}
@@ -0,0 +1,96 @@
+package settings
+
+import (
+	"encoding/json"
+
+	"server/log"
+)
+
+// info of torrent files is saved by hash as json map of file path to value
+
+// GetMediaInfo reads saved media info of file into info, false if not saved
+func GetMediaInfo(hash, path string, info interface{}) bool {
+	return getFileInfo("Media", hash, path, info)
+}
+
+func SetMediaInfo(hash, path string, info interface{}) {
+	setFileInfo("Media", hash, path, info)
+}
+
+// GetArchiveEntries reads saved entries of archive file in torrent
+func GetArchiveEntries(hash, path string, entries interface{}) bool {
+	return getFileInfo("Archives", hash, path, entries)
+}
+
+func SetArchiveEntries(hash, path string, entries interface{}) {
+	setFileInfo("Archives", hash, path, entries)
+}
+
+// GetDiscTitles reads saved titles of DVD or Blu-ray disc by its root
+func GetDiscTitles(hash, root string, titles interface{}) bool {
+	return getFileInfo("Discs", hash, root, titles)
+}
+
+func SetDiscTitles(hash, root string, titles interface{}) {
+	setFileInfo("Discs", hash, root, titles)
+}
+
+// GetFileIDs returns saved ids of virtual files of torrent by path
+func GetFileIDs(hash string) map[string]int {
+	ids := make(map[string]int)
+	if buf := tdb.Get("FileIDs", hash); len(buf) > 0 {
+		if err := json.Unmarshal(buf, &ids); err != nil {
+			log.TLogln("Error get file ids:", err)
+		}
+	}
+	return ids
+}
+
+func SetFileIDs(hash string, ids map[string]int) {
+	buf, err := json.Marshal(ids)
+	if err != nil {
+		log.TLogln("Error set file ids:", err)
+		return
+	}
+	tdb.Set("FileIDs", hash, buf)
+}
+
+// RemFilesInfo removes media info, archive entries, disc titles and ids of
+// virtual files of torrent
+func RemFilesInfo(hash string) {
+	tdb.Rem("Media", hash)
+	tdb.Rem("Archives", hash)
+	tdb.Rem("Discs", hash)
+	tdb.Rem("FileIDs", hash)
+}
+
+func getFileInfo(xpath, hash, path string, v interface{}) bool {
+	buf := tdb.Get(xpath, hash)
+	if len(buf) == 0 {
+		return false
+	}
+	var files map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &files); err != nil {
+		return false
+	}
+	data, ok := files[path]
+	return ok && json.Unmarshal(data, v) == nil
+}
+
+func setFileInfo(xpath, hash, path string, v interface{}) {
+	files := make(map[string]json.RawMessage)
+	if buf := tdb.Get(xpath, hash); len(buf) > 0 {
+		json.Unmarshal(buf, &files)
+	}
+	data, err := json.Marshal(v)
+	if err == nil {
+		files[path] = data
+		var buf []byte
+		if buf, err = json.Marshal(files); err == nil {
+			tdb.Set(xpath, hash, buf)
+		}
+	}
+	if err != nil {
+		log.TLogln("Error set "+xpath+" info:", err)
+	}
+}
@@ -0,0 +1,91 @@
+package settings
+
+import (
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+
+	"server/log"
+)
+
+type schemaMigration struct {
+	version int
+	name    string
+	migrate func(tx *bolt.Tx) error
+}
+
+// migrations run in order, each in own transaction with version bump,
+// append new ones to the end and never change applied
+var migrations = []schemaMigration{
+	{1, "index torrents by time and title", indexTorrents},
+}
+
+func SchemaVersion() int {
+	buf := tdb.Get("Settings", "SchemaVersion")
+	if len(buf) == 0 {
+		return 0
+	}
+	ver, err := strconv.Atoi(string(buf))
+	if err != nil {
+		return 0
+	}
+	return ver
+}
+
+func migrateSchema() {
+	current := SchemaVersion()
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if ReadOnly {
+			log.TLogln("DB schema version", current, "is outdated, but db in read-only mode")
+			return
+		}
+		log.TLogln("Migrate db schema to version", m.version, "-", m.name)
+		err := tdb.Update(func(tx *bolt.Tx) error {
+			if err := m.migrate(tx); err != nil {
+				return err
+			}
+			buckt, err := tx.CreateBucketIfNotExists([]byte("Settings"))
+			if err != nil {
+				return err
+			}
+			return buckt.Put([]byte("SchemaVersion"), []byte(strconv.Itoa(m.version)))
+		})
+		if err != nil {
+			log.TLogln("Error migrate db schema to version", m.version, ":", err)
+			return
+		}
+		current = m.version
+	}
+}
+
+func torrentsIndexed() bool {
+	return SchemaVersion() >= 1
+}
+
+func indexTorrents(tx *bolt.Tx) error {
+	tx.DeleteBucket(torrentsByTimeBucket)
+	tx.DeleteBucket(torrentsByTitleBucket)
+	buckt := tx.Bucket(torrentsBucket)
+	if buckt == nil {
+		return nil
+	}
+	var list []*TorrentDB
+	err := buckt.ForEach(func(_, v []byte) error {
+		if torr := decodeTorrent(v); torr != nil {
+			list = append(list, torr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, torr := range list {
+		if err = putTorrent(tx, torr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
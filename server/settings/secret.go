@@ -0,0 +1,45 @@
+package settings
+
+import (
+	"crypto/rand"
+	"sync"
+
+	"server/log"
+)
+
+// secret signs stream links issued for players without http auth
+var (
+	secret   []byte
+	muSecret sync.Mutex
+)
+
+// StreamSecret returns key of signed stream links, it's created on first use
+func StreamSecret() []byte {
+	muSecret.Lock()
+	defer muSecret.Unlock()
+	if len(secret) == 0 {
+		secret = tdb.Get("Settings", "StreamSecret")
+	}
+	if len(secret) == 0 {
+		secret = newSecret()
+	}
+	return secret
+}
+
+// RotateStreamSecret replaces key, all issued links stop working
+func RotateStreamSecret() {
+	muSecret.Lock()
+	defer muSecret.Unlock()
+	secret = newSecret()
+	log.TLogln("Stream links secret rotated")
+}
+
+func newSecret() []byte {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.TLogln("Error create stream secret", err)
+		return nil
+	}
+	tdb.Set("Settings", "StreamSecret", buf)
+	return buf
+}
@@ -16,6 +16,10 @@ var (
 	PubIPv4  string
 	PubIPv6  string
 	TorAddr  string
+	WebBind  string
+	// ContentRoot is dir with local content for torrents created by api,
+	// empty disables creating by api
+	ContentRoot string
 )
 
 func InitSets(readOnly bool) {
@@ -25,10 +29,22 @@ func InitSets(readOnly bool) {
 		log.TLogln("Error open db:", filepath.Join(Path, "config.db"))
 		os.Exit(1)
 	}
+	migrateSchema()
 	loadBTSets()
 	Migrate()
 }
 
-func CloseDB() {
-	tdb.CloseDB()
+// DBState reports whether db is open and accepts writes
+func DBState() (open, writable bool) {
+	if tdb == nil || !tdb.IsOpen() {
+		return false, false
+	}
+	return true, !ReadOnly && !tdb.db.IsReadOnly()
+}
+
+func CloseDB() error {
+	if tdb == nil {
+		return nil
+	}
+	return tdb.CloseDB()
 }
@@ -1,23 +1,33 @@
 package settings
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"sort"
-	"sync"
+	"strings"
 
 	"github.com/anacrolix/torrent"
 	"github.com/anacrolix/torrent/metainfo"
+	bolt "go.etcd.io/bbolt"
+
+	"server/log"
 )
 
 type TorrentDB struct {
 	*torrent.TorrentSpec
 
-	Title  string `json:"title,omitempty"`
-	Poster string `json:"poster,omitempty"`
-	Data   string `json:"data,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Poster   string `json:"poster,omitempty"`
+	Data     string `json:"data,omitempty"`
+	Category string `json:"category,omitempty"`
 
 	Timestamp int64 `json:"timestamp,omitempty"`
 	Size      int64 `json:"size,omitempty"`
+
+	SeedPath   string `json:"seed_path,omitempty"`
+	SelectOnly []int  `json:"select_only,omitempty"`
 }
 
 type File struct {
@@ -26,48 +36,205 @@ type File struct {
 	Size int64  `json:"size,omitempty"`
 }
 
-var mu sync.Mutex
+const (
+	OrderByTime  = "time"
+	OrderByTitle = "title"
+)
 
-func AddTorrent(torr *TorrentDB) {
-	list := ListTorrent()
-	mu.Lock()
-	find := -1
-	for i, db := range list {
-		if db.InfoHash.HexString() == torr.InfoHash.HexString() {
-			find = i
-			break
-		}
+var (
+	torrentsBucket        = []byte("Torrents")
+	torrentsByTimeBucket  = []byte("TorrentsByTime")
+	torrentsByTitleBucket = []byte("TorrentsByTitle")
+)
+
+// timeIndexKey sorts by timestamp, hash keeps keys unique
+func timeIndexKey(t *TorrentDB) []byte {
+	key := make([]byte, 8, 8+len(t.InfoHash))
+	binary.BigEndian.PutUint64(key, uint64(t.Timestamp))
+	return append(key, t.InfoHash.Bytes()...)
+}
+
+func titleIndexKey(t *TorrentDB) []byte {
+	key := []byte(strings.ToLower(t.Title))
+	key = append(key, 0)
+	return append(key, t.InfoHash.Bytes()...)
+}
+
+func putTorrent(tx *bolt.Tx, torr *TorrentDB) error {
+	buckt, err := tx.CreateBucketIfNotExists(torrentsBucket)
+	if err != nil {
+		return err
+	}
+	byTime, err := tx.CreateBucketIfNotExists(torrentsByTimeBucket)
+	if err != nil {
+		return err
 	}
-	if find != -1 {
-		list[find] = torr
-	} else {
-		list = append(list, torr)
+	byTitle, err := tx.CreateBucketIfNotExists(torrentsByTitleBucket)
+	if err != nil {
+		return err
+	}
+
+	key := []byte(torr.InfoHash.HexString())
+	if old := decodeTorrent(buckt.Get(key)); old != nil {
+		byTime.Delete(timeIndexKey(old))
+		byTitle.Delete(titleIndexKey(old))
+	}
+
+	buf, err := json.Marshal(torr)
+	if err != nil {
+		return err
 	}
-	for _, db := range list {
-		buf, err := json.Marshal(db)
-		if err == nil {
-			tdb.Set("Torrents", db.InfoHash.HexString(), buf)
+	if err = buckt.Put(key, buf); err != nil {
+		return err
+	}
+	if err = byTime.Put(timeIndexKey(torr), key); err != nil {
+		return err
+	}
+	return byTitle.Put(titleIndexKey(torr), key)
+}
+
+func deleteTorrent(tx *bolt.Tx, hash metainfo.Hash) error {
+	buckt := tx.Bucket(torrentsBucket)
+	if buckt == nil {
+		return nil
+	}
+	key := []byte(hash.HexString())
+	if old := decodeTorrent(buckt.Get(key)); old != nil {
+		if byTime := tx.Bucket(torrentsByTimeBucket); byTime != nil {
+			byTime.Delete(timeIndexKey(old))
+		}
+		if byTitle := tx.Bucket(torrentsByTitleBucket); byTitle != nil {
+			byTitle.Delete(titleIndexKey(old))
 		}
 	}
-	mu.Unlock()
+	return buckt.Delete(key)
 }
 
+func decodeTorrent(buf []byte) *TorrentDB {
+	if len(buf) == 0 {
+		return nil
+	}
+	var torr *TorrentDB
+	err := json.Unmarshal(buf, &torr)
+	if err != nil || torr == nil || torr.TorrentSpec == nil {
+		return nil
+	}
+	return torr
+}
+
+func AddTorrent(torr *TorrentDB) {
+	if torr == nil || torr.TorrentSpec == nil {
+		return
+	}
+	err := tdb.Update(func(tx *bolt.Tx) error {
+		return putTorrent(tx, torr)
+	})
+	if err != nil && err != ErrReadOnly {
+		log.TLogln("Error add torrent to db:", err)
+	}
+}
+
+func GetTorrent(hash metainfo.Hash) *TorrentDB {
+	var torr *TorrentDB
+	tdb.View(func(tx *bolt.Tx) error {
+		if buckt := tx.Bucket(torrentsBucket); buckt != nil {
+			torr = decodeTorrent(buckt.Get([]byte(hash.HexString())))
+		}
+		return nil
+	})
+	return torr
+}
+
+// ListTorrent returns all torrents, newest first
 func ListTorrent() []*TorrentDB {
-	mu.Lock()
-	defer mu.Unlock()
+	if !torrentsIndexed() {
+		return scanTorrents()
+	}
+	list, _ := ListTorrentPage(OrderByTime, "", 0)
+	return list
+}
 
-	var list []*TorrentDB
-	keys := tdb.List("Torrents")
-	for _, key := range keys {
-		buf := tdb.Get("Torrents", key)
-		if len(buf) > 0 {
-			var torr *TorrentDB
-			err := json.Unmarshal(buf, &torr)
-			if err == nil {
+// ListTorrentPage walks the index by order starting after cursor, limit 0 is
+// unlimited. Returns cursor for next page, empty if the end reached.
+func ListTorrentPage(order, cursor string, limit int) ([]*TorrentDB, string) {
+	return ListTorrentPageFilter(order, cursor, limit, nil)
+}
+
+// ListTorrentPageFilter is ListTorrentPage of torrents passing filter
+func ListTorrentPageFilter(order, cursor string, limit int, filter func(*TorrentDB) bool) ([]*TorrentDB, string) {
+	idxName := torrentsByTimeBucket
+	desc := true
+	if order == OrderByTitle {
+		idxName = torrentsByTitleBucket
+		desc = false
+	}
+	after, err := hex.DecodeString(cursor)
+	if err != nil {
+		after = nil
+	}
+
+	list := make([]*TorrentDB, 0)
+	next := ""
+	tdb.View(func(tx *bolt.Tx) error {
+		buckt := tx.Bucket(torrentsBucket)
+		idx := tx.Bucket(idxName)
+		if buckt == nil || idx == nil {
+			return nil
+		}
+		c := idx.Cursor()
+		var k, v []byte
+		switch {
+		case len(after) == 0 && desc:
+			k, v = c.Last()
+		case len(after) == 0:
+			k, v = c.First()
+		default:
+			k, v = c.Seek(after)
+			if desc {
+				if k == nil {
+					k, v = c.Last()
+				}
+				if k != nil && bytes.Compare(k, after) >= 0 {
+					k, v = c.Prev()
+				}
+			} else if k != nil && bytes.Equal(k, after) {
+				k, v = c.Next()
+			}
+		}
+		for k != nil {
+			if limit > 0 && len(list) >= limit {
+				next = hex.EncodeToString(after)
+				break
+			}
+			if torr := decodeTorrent(buckt.Get(v)); torr != nil && (filter == nil || filter(torr)) {
 				list = append(list, torr)
 			}
+			after = k
+			if desc {
+				k, v = c.Prev()
+			} else {
+				k, v = c.Next()
+			}
 		}
-	}
+		return nil
+	})
+	return list, next
+}
+
+func scanTorrents() []*TorrentDB {
+	var list []*TorrentDB
+	tdb.View(func(tx *bolt.Tx) error {
+		buckt := tx.Bucket(torrentsBucket)
+		if buckt == nil {
+			return nil
+		}
+		return buckt.ForEach(func(_, v []byte) error {
+			if torr := decodeTorrent(v); torr != nil {
+				list = append(list, torr)
+			}
+			return nil
+		})
+	})
 	sort.Slice(list, func(i, j int) bool {
 		return list[i].Timestamp > list[j].Timestamp
 	})
@@ -75,7 +242,10 @@ func ListTorrent() []*TorrentDB {
 }
 
 func RemTorrent(hash metainfo.Hash) {
-	mu.Lock()
-	tdb.Rem("Torrents", hash.HexString())
-	mu.Unlock()
+	err := tdb.Update(func(tx *bolt.Tx) error {
+		return deleteTorrent(tx, hash)
+	})
+	if err != nil && err != ErrReadOnly {
+		log.TLogln("Error rem torrent from db:", err)
+	}
 }
@@ -0,0 +1,105 @@
+package settings
+
+import (
+	"net/url"
+)
+
+// data of http auth users is kept in Users/<name>/..., empty user is
+// server without auth or not authorized stream
+
+// DLNAUser signs stream links of DLNA, it shares all torrents, basic auth
+// user can't have ":" in name
+const DLNAUser = ":dlna"
+
+var (
+	// Admins are users who see torrents and viewed of all users
+	Admins []string
+	// UserLibraries hides torrents added by other users
+	UserLibraries bool
+)
+
+// IsAdmin returns true for admins and for server without auth
+func IsAdmin(user string) bool {
+	if user == "" {
+		return !HttpAuth
+	}
+	for _, a := range Admins {
+		if a == user {
+			return true
+		}
+	}
+	return false
+}
+
+func userXPath(user, xpath string) string {
+	if user == "" {
+		return xpath
+	}
+	return "Users/" + url.PathEscape(user) + "/" + xpath
+}
+
+// ListUsers returns users having data in db
+func ListUsers() []string {
+	var ret []string
+	for _, name := range tdb.List("Users") {
+		if user, err := url.PathUnescape(name); err == nil {
+			ret = append(ret, user)
+		}
+	}
+	return ret
+}
+
+// AddUserTorrent adds torrent to library of user
+func AddUserTorrent(user, hash string) {
+	if user != "" {
+		tdb.Set(userXPath(user, "Torrents"), hash, []byte{1})
+	}
+}
+
+// RemUserTorrent removes torrent from library of user, returns true if
+// other users have it
+func RemUserTorrent(user, hash string) bool {
+	if user != "" {
+		tdb.Rem(userXPath(user, "Torrents"), hash)
+	}
+	for _, u := range ListUsers() {
+		if len(tdb.Get(userXPath(u, "Torrents"), hash)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// RemTorrentUsers removes torrent from libraries of all users
+func RemTorrentUsers(hash string) {
+	for _, u := range ListUsers() {
+		tdb.Rem(userXPath(u, "Torrents"), hash)
+	}
+}
+
+// UserTorrents returns hashes of torrents visible for user, nil if all are
+func UserTorrents(user string) map[string]bool {
+	if !UserLibraries || IsAdmin(user) || user == DLNAUser {
+		return nil
+	}
+	ret := make(map[string]bool)
+	if user == "" {
+		// not authorized with http auth
+		return ret
+	}
+	for _, hash := range tdb.List(userXPath(user, "Torrents")) {
+		ret[hash] = true
+	}
+	return ret
+}
+
+// UserHasTorrent checks that torrent is visible for user
+func UserHasTorrent(user, hash string) bool {
+	if !UserLibraries || IsAdmin(user) || user == DLNAUser {
+		return true
+	}
+	if user == "" {
+		return false
+	}
+	return len(tdb.Get(userXPath(user, "Torrents"), hash)) > 0
+}
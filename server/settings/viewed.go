@@ -2,35 +2,102 @@ package settings
 
 import (
 	"encoding/json"
+	"sort"
+	"sync"
+	"time"
 
 	"server/log"
 )
 
+// Viewed is file of torrent opened by player with its resume point,
+// offset is in bytes and position in seconds, position is 0 if unknown,
+// viewed are kept for each user
 type Viewed struct {
-	Hash      string `json:"hash"`
-	FileIndex int    `json:"file_index"`
+	User      string  `json:"user,omitempty"`
+	Hash      string  `json:"hash"`
+	FileIndex int     `json:"file_index"`
+	Offset    int64   `json:"offset,omitempty"`
+	Position  float64 `json:"position,omitempty"`
+	Percent   float64 `json:"percent,omitempty"`
+	Watched   bool    `json:"watched,omitempty"`
+	Updated   int64   `json:"updated,omitempty"`
 }
 
+// viewedPos is saved by hash in map of file indexes, old db has empty
+// objects there
+type viewedPos struct {
+	Offset   int64   `json:"offset,omitempty"`
+	Position float64 `json:"position,omitempty"`
+	Percent  float64 `json:"percent,omitempty"`
+	Watched  bool    `json:"watched,omitempty"`
+	Updated  int64   `json:"updated,omitempty"`
+}
+
+var (
+	muViewedCopy sync.Mutex
+	viewedCopied = make(map[string]bool)
+)
+
+// viewedXPath returns xpath of viewed of user, viewed saved before users got
+// own lists are copied to list of user on its first use
+func viewedXPath(user string) string {
+	xpath := userXPath(user, "Viewed")
+	if user == "" {
+		return xpath
+	}
+	muViewedCopy.Lock()
+	defer muViewedCopy.Unlock()
+	if viewedCopied[user] {
+		return xpath
+	}
+	viewedCopied[user] = true
+	if ReadOnly || len(tdb.Get(userXPath(user, "Settings"), "ViewedCopied")) > 0 {
+		return xpath
+	}
+	for _, hash := range tdb.List("Viewed") {
+		if len(tdb.Get(xpath, hash)) > 0 {
+			continue
+		}
+		if buf := tdb.Get("Viewed", hash); len(buf) > 0 {
+			tdb.Set(xpath, hash, buf)
+		}
+	}
+	tdb.Set(userXPath(user, "Settings"), "ViewedCopied", []byte{1})
+	return xpath
+}
+
+// SetViewed adds file to viewed, resume point is changed if offset or
+// position is set, file is watched from WatchedPercent of resume point
 func SetViewed(vv *Viewed) {
-	var indexes map[int]struct{}
+	xpath := viewedXPath(vv.User)
+	files := make(map[int]*viewedPos)
+	buf := tdb.Get(xpath, vv.Hash)
 	var err error
-
-	buf := tdb.Get("Viewed", vv.Hash)
-	if len(buf) == 0 {
-		indexes = make(map[int]struct{})
-		indexes[vv.FileIndex] = struct{}{}
-		buf, err = json.Marshal(indexes)
-		if err == nil {
-			tdb.Set("Viewed", vv.Hash, buf)
+	if len(buf) > 0 {
+		err = json.Unmarshal(buf, &files)
+	}
+	if err == nil {
+		pos := files[vv.FileIndex]
+		if pos == nil {
+			pos = new(viewedPos)
+			files[vv.FileIndex] = pos
+		}
+		if vv.Offset != 0 || vv.Position != 0 {
+			pos.Offset = vv.Offset
+			pos.Position = vv.Position
+			pos.Percent = vv.Percent
+			pos.Watched = BTsets != nil && pos.Percent >= float64(BTsets.WatchedPercent)
+		}
+		if vv.Watched {
+			pos.Watched = true
+		}
+		pos.Updated = vv.Updated
+		if pos.Updated == 0 {
+			pos.Updated = time.Now().Unix()
 		}
-	} else {
-		err = json.Unmarshal(buf, &indexes)
+		buf, err = json.Marshal(files)
 		if err == nil {
-			indexes[vv.FileIndex] = struct{}{}
-			buf, err = json.Marshal(indexes)
-			if err == nil {
-				tdb.Set("Viewed", vv.Hash, buf)
-			}
+			tdb.Set(xpath, vv.Hash, buf)
 		}
 	}
 	if err != nil {
@@ -39,18 +106,19 @@ func SetViewed(vv *Viewed) {
 }
 
 func RemViewed(vv *Viewed) {
-	buf := tdb.Get("Viewed", vv.Hash)
-	var indeces map[int]struct{}
-	err := json.Unmarshal(buf, &indeces)
+	xpath := viewedXPath(vv.User)
+	buf := tdb.Get(xpath, vv.Hash)
+	var files map[int]*viewedPos
+	err := json.Unmarshal(buf, &files)
 	if err == nil {
 		if vv.FileIndex != -1 {
-			delete(indeces, vv.FileIndex)
-			buf, err = json.Marshal(indeces)
+			delete(files, vv.FileIndex)
+			buf, err = json.Marshal(files)
 			if err == nil {
-				tdb.Set("Viewed", vv.Hash, buf)
+				tdb.Set(xpath, vv.Hash, buf)
 			}
 		} else {
-			tdb.Rem("Viewed", vv.Hash)
+			tdb.Rem(xpath, vv.Hash)
 		}
 	}
 	if err != nil {
@@ -58,43 +126,48 @@ func RemViewed(vv *Viewed) {
 	}
 }
 
-func ListViewed(hash string) []*Viewed {
-	var err error
-	if hash != "" {
-		buf := tdb.Get("Viewed", hash)
+// ListViewed returns viewed files of user for torrent or for all torrents if
+// hash is empty, ordered by hash and file index
+func ListViewed(user, hash string) []*Viewed {
+	xpath := viewedXPath(user)
+	keys := []string{hash}
+	if hash == "" {
+		keys = tdb.List(xpath)
+	}
+	ret := []*Viewed{}
+	for _, key := range keys {
+		buf := tdb.Get(xpath, key)
 		if len(buf) == 0 {
-			return []*Viewed{}
+			continue
 		}
-		var indeces map[int]struct{}
-		err = json.Unmarshal(buf, &indeces)
-		if err == nil {
-			var ret []*Viewed
-			for i, _ := range indeces {
-				ret = append(ret, &Viewed{hash, i})
-			}
-			return ret
+		var files map[int]*viewedPos
+		if err := json.Unmarshal(buf, &files); err != nil {
+			log.TLogln("Error list viewed:", err)
+			continue
 		}
-	} else {
-		var ret []*Viewed
-		keys := tdb.List("Viewed")
-		for _, key := range keys {
-			buf := tdb.Get("Viewed", key)
-			if len(buf) == 0 {
-				return []*Viewed{}
-			}
-			var indeces map[int]struct{}
-			err = json.Unmarshal(buf, &indeces)
-			if err == nil {
-				for i, _ := range indeces {
-					ret = append(ret, &Viewed{key, i})
-				}
+		for i, pos := range files {
+			v := &Viewed{User: user, Hash: key, FileIndex: i}
+			if pos != nil {
+				v.Offset, v.Position, v.Percent = pos.Offset, pos.Position, pos.Percent
+				v.Watched, v.Updated = pos.Watched, pos.Updated
 			}
+			ret = append(ret, v)
 		}
-		return ret
 	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Hash != ret[j].Hash {
+			return ret[i].Hash < ret[j].Hash
+		}
+		return ret[i].FileIndex < ret[j].FileIndex
+	})
+	return ret
+}
 
-	if err != nil {
-		log.TLogln("Error list viewed:", err)
+// ListAllViewed returns viewed of all users
+func ListAllViewed(hash string) []*Viewed {
+	ret := ListViewed("", hash)
+	for _, user := range ListUsers() {
+		ret = append(ret, ListViewed(user, hash)...)
 	}
-	return []*Viewed{}
+	return ret
 }
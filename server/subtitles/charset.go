@@ -0,0 +1,80 @@
+package subtitles
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Decode converts subtitles text to utf-8, charset is name like
+// "windows-1251" or empty to detect by BOM and content
+func Decode(data []byte, charset string) (string, error) {
+	if charset != "" {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return "", err
+		}
+		return decodeWith(enc, data)
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], binary.LittleEndian), nil
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], binary.BigEndian), nil
+	case utf8.Valid(data):
+		return string(data), nil
+	}
+	return decodeWith(detect8bit(data), data)
+}
+
+func decodeWith(enc encoding.Encoding, data []byte) (string, error) {
+	buf, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimPrefix(buf, []byte("\uFEFF"))), nil
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	u := make([]uint16, len(data)/2)
+	for i := range u {
+		u[i] = order.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// detect8bit chooses between cp1251 and cp1252, russian words consist of
+// high bytes only, western accents are surrounded by ascii letters
+func detect8bit(data []byte) encoding.Encoding {
+	isHigh := func(b byte) bool { return b >= 0xC0 || b == 0xA8 || b == 0xB8 }
+	isASCII := func(b byte) bool { return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' }
+	cyr, latin := 0, 0
+	for i, b := range data {
+		if !isHigh(b) {
+			continue
+		}
+		var prev, next byte
+		if i > 0 {
+			prev = data[i-1]
+		}
+		if i+1 < len(data) {
+			next = data[i+1]
+		}
+		if isASCII(prev) || isASCII(next) {
+			latin++
+		} else if isHigh(prev) || isHigh(next) {
+			cyr++
+		}
+	}
+	if cyr > latin {
+		return charmap.Windows1251
+	}
+	return charmap.Windows1252
+}
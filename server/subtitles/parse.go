@@ -0,0 +1,224 @@
+package subtitles
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultFPS = 23.976
+
+var (
+	reSRTTime       = regexp.MustCompile(`^\s*((?:\d+:)?\d{1,2}:\d{1,2}[,.]\d{1,3})\s*-->\s*((?:\d+:)?\d{1,2}:\d{1,2}[,.]\d{1,3})`)
+	reMicroDVD      = regexp.MustCompile(`^\{(\d+)\}\{(\d*)\}(.*)$`)
+	reSubViewerTime = regexp.MustCompile(`^(\d+:\d{2}:\d{2}\.\d{1,3}),(\d+:\d{2}:\d{2}\.\d{1,3})\s*$`)
+	reASSStyle      = regexp.MustCompile(`\\([ibu])([01])`)
+	reASSDrawing    = regexp.MustCompile(`\\p[1-9]`)
+)
+
+// parseTime parses [h:]mm:ss[.,]fraction
+func parseTime(s string) (time.Duration, bool) {
+	s = strings.Replace(s, ",", ".", 1)
+	frac := ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s, frac = s[:i], s[i+1:]
+	}
+	parts := strings.Split(s, ":")
+	var secs int64
+	for _, p := range parts {
+		n, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		secs = secs*60 + n
+	}
+	ms := int64(0)
+	if frac != "" {
+		if len(frac) > 3 {
+			frac = frac[:3]
+		}
+		n, err := strconv.ParseInt(frac+strings.Repeat("0", 3-len(frac)), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		ms = n
+	}
+	return time.Duration(secs)*time.Second + time.Duration(ms)*time.Millisecond, true
+}
+
+// parseSRT parses SubRip and WebVTT cues
+func parseSRT(text string) []*cue {
+	var cues []*cue
+	var cur *cue
+	var lines []string
+	flush := func() {
+		if cur != nil {
+			cur.text = cleanText(strings.Join(lines, "\n"))
+			cues = append(cues, cur)
+		}
+		cur, lines = nil, nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if m := reSRTTime.FindStringSubmatch(line); m != nil {
+			flush()
+			start, ok1 := parseTime(m[1])
+			end, ok2 := parseTime(m[2])
+			if ok1 && ok2 {
+				cur = &cue{start: start, end: end}
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return cues
+}
+
+// parseASS parses Dialogue lines of Advanced SubStation Alpha
+func parseASS(text string) []*cue {
+	format := []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+	var cues []*cue
+	inEvents := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(line, "[events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "format":
+			format = strings.Split(strings.ToLower(value), ",")
+			for i := range format {
+				format[i] = strings.TrimSpace(format[i])
+			}
+		case "dialogue":
+			fields := strings.SplitN(strings.TrimSpace(value), ",", len(format))
+			if len(fields) != len(format) {
+				continue
+			}
+			c := &cue{}
+			ok := true
+			for i, name := range format {
+				switch name {
+				case "start":
+					c.start, ok = parseTime(strings.TrimSpace(fields[i]))
+				case "end":
+					c.end, ok = parseTime(strings.TrimSpace(fields[i]))
+				case "text":
+					c.text = assText(fields[i])
+				}
+				if !ok {
+					break
+				}
+			}
+			if ok {
+				cues = append(cues, c)
+			}
+		}
+	}
+	return cues
+}
+
+func assText(s string) string {
+	s = reOverride.ReplaceAllStringFunc(s, func(block string) string {
+		if reASSDrawing.MatchString(block) {
+			return "\x00"
+		}
+		var tags strings.Builder
+		for _, m := range reASSStyle.FindAllStringSubmatch(block, -1) {
+			if m[2] == "1" {
+				tags.WriteString("<" + m[1] + ">")
+			} else {
+				tags.WriteString("</" + m[1] + ">")
+			}
+		}
+		return tags.String()
+	})
+	if strings.Contains(s, "\x00") {
+		// vector drawing, not text
+		return ""
+	}
+	s = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(s)
+	return cleanText(s)
+}
+
+// parseSUB parses MicroDVD or SubViewer text subtitles
+func parseSUB(text string, fps float64) ([]*cue, error) {
+	lines := strings.Split(text, "\n")
+	var cues []*cue
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if m := reMicroDVD.FindStringSubmatch(line); m != nil {
+			start, _ := strconv.ParseFloat(m[1], 64)
+			end, _ := strconv.ParseFloat(m[2], 64)
+			// first cue may set frame rate, e.g. {1}{1}25.000
+			if len(cues) == 0 && start <= 1 && end <= 1 {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(m[3]), 64); err == nil && f > 0 {
+					if fps <= 0 {
+						fps = f
+					}
+					continue
+				}
+			}
+			if fps <= 0 {
+				fps = defaultFPS
+			}
+			c := &cue{start: frameTime(start, fps), text: microDVDText(m[3])}
+			if m[2] == "" {
+				c.end = c.start + 3*time.Second
+			} else {
+				c.end = frameTime(end, fps)
+			}
+			cues = append(cues, c)
+			continue
+		}
+		if m := reSubViewerTime.FindStringSubmatch(line); m != nil && i+1 < len(lines) {
+			start, ok1 := parseTime(m[1])
+			end, ok2 := parseTime(m[2])
+			i++
+			if ok1 && ok2 {
+				txt := strings.ReplaceAll(lines[i], "[br]", "\n")
+				txt = strings.ReplaceAll(txt, "[BR]", "\n")
+				cues = append(cues, &cue{start: start, end: end, text: cleanText(txt)})
+			}
+		}
+	}
+	if len(cues) == 0 {
+		// binary VobSub or unknown text format
+		return nil, ErrUnsupported
+	}
+	return cues, nil
+}
+
+func frameTime(frame, fps float64) time.Duration {
+	return time.Duration(frame / fps * float64(time.Second))
+}
+
+func microDVDText(s string) string {
+	italic := false
+	if strings.HasPrefix(s, "{Y:i}") || strings.HasPrefix(s, "{Y:I}") {
+		italic = true
+	}
+	lines := strings.Split(s, "|")
+	for i, l := range lines {
+		if italic || strings.HasPrefix(strings.ToLower(l), "{y:i}") || strings.HasPrefix(l, "/") {
+			lines[i] = "<i>" + strings.TrimPrefix(l, "/") + "</i>"
+		}
+	}
+	return cleanText(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,142 @@
+package subtitles
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var extSubtitles = map[string]interface{}{
+	".srt": nil,
+	".vtt": nil,
+	".ass": nil,
+	".ssa": nil,
+	".sub": nil,
+}
+
+var ErrUnsupported = errors.New("unsupported subtitles format")
+
+// IsSubtitle checks file extension of text subtitles
+func IsSubtitle(path string) bool {
+	_, ok := extSubtitles[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// Label returns track name from subtitle file name, e.g. "Rus.Forced" for
+// Movie.mkv and Subs/Movie.Rus.Forced.srt, or directory name
+func Label(videoPath, subPath string) string {
+	video := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	name := strings.TrimSuffix(filepath.Base(subPath), filepath.Ext(subPath))
+	name = strings.Trim(strings.Replace(name, video, "", 1), " ._-")
+	if name == "" && filepath.Dir(subPath) != filepath.Dir(videoPath) {
+		name = filepath.Base(filepath.Dir(subPath))
+	}
+	return name
+}
+
+type cue struct {
+	start, end time.Duration
+	text       string
+}
+
+// Options of conversion
+type Options struct {
+	Offset  time.Duration // shift of all cues
+	Charset string        // source charset, empty to detect
+	FPS     float64       // frame rate for MicroDVD, 0 to use file or default
+}
+
+// ToVTT converts srt, vtt, ass, ssa or text sub file to WebVTT
+func ToVTT(data []byte, ext string, opts Options) ([]byte, error) {
+	text, err := Decode(data, opts.Charset)
+	if err != nil {
+		return nil, err
+	}
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+
+	var cues []*cue
+	switch strings.ToLower(ext) {
+	case ".srt", ".vtt":
+		cues = parseSRT(text)
+	case ".ass", ".ssa":
+		cues = parseASS(text)
+	case ".sub":
+		cues, err = parseSUB(text, opts.FPS)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+	return writeVTT(cues, opts.Offset), nil
+}
+
+func writeVTT(cues []*cue, offset time.Duration) []byte {
+	sort.SliceStable(cues, func(i, j int) bool {
+		return cues[i].start < cues[j].start
+	})
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n\n")
+	for _, c := range cues {
+		start, end := c.start+offset, c.end+offset
+		if end <= 0 || end <= start || strings.TrimSpace(c.text) == "" {
+			continue
+		}
+		if start < 0 {
+			start = 0
+		}
+		sb.WriteString(formatTime(start) + " --> " + formatTime(end) + "\n")
+		sb.WriteString(c.text + "\n\n")
+	}
+	return []byte(sb.String())
+}
+
+func formatTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+var (
+	reOverride = regexp.MustCompile(`\{[^}]*\}`)
+	reTag      = regexp.MustCompile(`</?([a-zA-Z]+)[^>]*>`)
+)
+
+// cleanText keeps only i, b and u tags and escapes the rest for WebVTT
+func cleanText(s string) string {
+	s = reOverride.ReplaceAllString(s, "")
+	var sb strings.Builder
+	last := 0
+	for _, m := range reTag.FindAllStringSubmatchIndex(s, -1) {
+		sb.WriteString(escape(s[last:m[0]]))
+		last = m[1]
+		tag := strings.ToLower(s[m[2]:m[3]])
+		if tag != "i" && tag != "b" && tag != "u" {
+			continue
+		}
+		if s[m[0]+1] == '/' {
+			sb.WriteString("</" + tag + ">")
+		} else {
+			sb.WriteString("<" + tag + ">")
+		}
+	}
+	sb.WriteString(escape(s[last:]))
+	lines := strings.Split(sb.String(), "\n")
+	ret := lines[:0]
+	for _, l := range lines {
+		// empty line ends cue in WebVTT
+		if l = strings.TrimSpace(l); l != "" {
+			ret = append(ret, l)
+		}
+	}
+	return strings.Join(ret, "\n")
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "-->", "--&gt;")
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
@@ -0,0 +1,61 @@
+package subtitles
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToVTT(t *testing.T) {
+	tests := []struct {
+		name, ext, data string
+		opts            Options
+		want            string
+	}{
+		{"srt", ".srt", "1\r\n00:00:01,500 --> 00:00:03,000\r\n<i>Hi</i> & <font color=red>bye</font>\r\n\r\n",
+			Options{}, "WEBVTT\n\n00:00:01.500 --> 00:00:03.000\n<i>Hi</i> &amp; bye\n\n"},
+		{"offset", ".srt", "1\n00:00:01,000 --> 00:00:02,000\nA\n\n2\n00:00:05,000 --> 00:00:06,000\nB\n",
+			Options{Offset: -2 * time.Second}, "WEBVTT\n\n00:00:03.000 --> 00:00:04.000\nB\n\n"},
+		{"ass", ".ass", "[Script Info]\nTitle: x\n[Events]\nFormat: Layer, Start, End, Style, Text\n" +
+			"Dialogue: 0,0:00:02.00,0:00:04.50,Default,{\\i1}One{\\i0}\\NTwo, three\n" +
+			"Dialogue: 0,0:00:05.00,0:00:06.00,Default,{\\p1}m 0 0 l 1 1{\\p0}\n",
+			Options{}, "WEBVTT\n\n00:00:02.000 --> 00:00:04.500\n<i>One</i>\nTwo, three\n\n"},
+		{"microdvd", ".sub", "{1}{1}25.000\n{25}{50}Line|/Italic\n",
+			Options{}, "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nLine\n<i>Italic</i>\n\n"},
+		{"cp1251", ".srt", "1\n00:00:01,000 --> 00:00:02,000\n\xcf\xf0\xe8\xe2\xe5\xf2\n",
+			Options{}, "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nПривет\n\n"},
+	}
+	for _, tt := range tests {
+		got, err := ToVTT([]byte(tt.data), tt.ext, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if _, err := ToVTT([]byte("\x00\x01binary"), ".sub", Options{}); err != ErrUnsupported {
+		t.Errorf("binary sub: %v", err)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	tests := []struct{ video, sub, want string }{
+		{"Movie.mkv", "Movie.Rus.Forced.srt", "Rus.Forced"},
+		{"Movie.mkv", "Subs/English/Movie.srt", "English"},
+		{"Movie.mkv", "Movie.srt", ""},
+	}
+	for _, tt := range tests {
+		if got := Label(tt.video, tt.sub); got != tt.want {
+			t.Errorf("Label(%q, %q) = %q, want %q", tt.video, tt.sub, got, tt.want)
+		}
+	}
+}
+
+func FuzzToVTT(f *testing.F) {
+	f.Add([]byte("1\n00:00:01,000 --> 00:00:02,000\nA\n"), ".srt")
+	f.Add([]byte("[Events]\nDialogue: 0,0:00:02.00,0:00:04.50,Default,,0,0,0,,{\\i1}A\n"), ".ass")
+	f.Add([]byte("{1}{1}25\n{25}{50}A|B\n"), ".sub")
+	f.Fuzz(func(t *testing.T, data []byte, ext string) {
+		ToVTT(data, ext, Options{})
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/anacrolix/torrent"
@@ -37,6 +38,7 @@ func LoadTorrent(tor *Torrent) *Torrent {
 	tr.Title = tor.Title
 	tr.Poster = tor.Poster
 	tr.Data = tor.Data
+	tr.Category = tor.Category
 	return tr
 }
 
@@ -70,6 +72,12 @@ func AddTorrent(spec *torrent.TorrentSpec, title, poster string, data string) (*
 			torr.Data = torDB.Data
 		}
 	}
+	if torr.Category == "" && torDB != nil {
+		torr.Category = torDB.Category
+	}
+	if len(torr.SelectOnly) == 0 && torDB != nil {
+		torr.SelectOnly = torDB.SelectOnly
+	}
 
 	return torr, nil
 }
@@ -97,6 +105,8 @@ func GetTorrent(hashHex string) *Torrent {
 				tr.Title = tor.Title
 				tr.Poster = tor.Poster
 				tr.Data = tor.Data
+				tr.Category = tor.Category
+				tr.SelectOnly = tor.SelectOnly
 				tr.Size = tor.Size
 				tr.Timestamp = tor.Timestamp
 				tr.GotInfo()
@@ -156,6 +166,17 @@ func RemTorrent(hashHex string) {
 	}
 	bts.RemoveTorrent(hash)
 	RemTorrentDB(hash)
+	sets.RemFilesInfo(hashHex)
+	sets.RemTorrentUsers(hashHex)
+}
+
+// RemUserTorrent removes torrent from library of user, torrent is removed
+// if other users don't have it
+func RemUserTorrent(user, hashHex string) {
+	if sets.UserLibraries && !sets.IsAdmin(user) && sets.RemUserTorrent(user, hashHex) {
+		return
+	}
+	RemTorrent(hashHex)
 }
 
 func ListTorrent() []*Torrent {
@@ -184,6 +205,52 @@ func ListTorrent() []*Torrent {
 	return ret
 }
 
+// ListUserTorrents returns torrents visible for user
+func ListUserTorrents(user string) []*Torrent {
+	list := ListTorrent()
+	visible := sets.UserTorrents(user)
+	if visible == nil {
+		return list
+	}
+	var ret []*Torrent
+	for _, t := range list {
+		if visible[t.Hash().HexString()] {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// ListTorrentPage returns saved torrents page by order, active torrents
+// replace their db copies
+func ListTorrentPage(order, cursor string, limit int) ([]*Torrent, string) {
+	return listTorrentPage(order, cursor, limit, nil)
+}
+
+// ListUserTorrentPage is ListTorrentPage of torrents visible for user
+func ListUserTorrentPage(user, order, cursor string, limit int) ([]*Torrent, string) {
+	var filter func(*sets.TorrentDB) bool
+	if visible := sets.UserTorrents(user); visible != nil {
+		filter = func(t *sets.TorrentDB) bool {
+			return visible[t.InfoHash.HexString()]
+		}
+	}
+	return listTorrentPage(order, cursor, limit, filter)
+}
+
+func listTorrentPage(order, cursor string, limit int, filter func(*sets.TorrentDB) bool) ([]*Torrent, string) {
+	list, next := sets.ListTorrentPageFilter(order, cursor, limit, filter)
+	ret := make([]*Torrent, 0, len(list))
+	for _, db := range list {
+		if tor := bts.GetTorrent(db.InfoHash); tor != nil {
+			ret = append(ret, tor)
+		} else {
+			ret = append(ret, fromTorrentDB(db))
+		}
+	}
+	return ret, next
+}
+
 func DropTorrent(hashHex string) {
 	hash := metainfo.NewHashFromHex(hashHex)
 	bts.RemoveTorrent(hash)
@@ -228,11 +295,33 @@ func dropAllTorrent() {
 	}
 }
 
+var (
+	shutdownChan = make(chan struct{})
+	shutdownOnce sync.Once
+)
+
+// Shutdown requests graceful shutdown of server
 func Shutdown() {
+	shutdownOnce.Do(func() {
+		log.TLogln("Received shutdown")
+		close(shutdownChan)
+	})
+}
+
+// ShutdownRequested is closed after Shutdown call
+func ShutdownRequested() <-chan struct{} {
+	return shutdownChan
+}
+
+// CloseAll closes torrents with caches and torrent client
+func CloseAll() {
+	if bts == nil {
+		return
+	}
+	for _, t := range bts.ListTorrents() {
+		t.Close()
+	}
 	bts.Disconnect()
-	sets.CloseDB()
-	log.TLogln("Received shutdown. Quit")
-	os.Exit(0)
 }
 
 func WriteStatus(w io.Writer) {
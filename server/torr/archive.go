@@ -0,0 +1,42 @@
+package torr
+
+import (
+	"io"
+	"sort"
+
+	"github.com/anacrolix/torrent"
+
+	"server/archive"
+	"server/settings"
+)
+
+// scanArchive reads entries of zip file in torrent, saved entries are
+// loaded from db
+func (t *Torrent) scanArchive(hash string, file *torrent.File) {
+	defer recoverScan("archive scan", hash)
+	path := file.Path()
+	var entries []*archive.Entry
+	if !settings.GetArchiveEntries(hash, path, &entries) {
+		err := t.scanFile(file, func(r io.ReadSeeker) (err error) {
+			entries, err = archive.ReadZipDir(archive.NewReaderAt(r), file.Length())
+			return
+		})
+		if err != nil {
+			probeLog.Debug("read archive failed", "hash", hash, "file", path, "error", err)
+			return
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+		settings.SetArchiveEntries(hash, path, entries)
+	}
+	if len(entries) == 0 {
+		return
+	}
+	t.muTorrent.Lock()
+	defer t.muTorrent.Unlock()
+	if t.archives == nil {
+		t.archives = make(map[string][]*archive.Entry)
+	}
+	t.archives[path] = entries
+}
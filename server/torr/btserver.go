@@ -2,19 +2,24 @@ package torr
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"strconv"
 	"sync"
 
+	"github.com/anacrolix/dht/v2"
 	"github.com/anacrolix/publicip"
 	"github.com/anacrolix/torrent"
 	"github.com/anacrolix/torrent/metainfo"
 
+	"server/proxy"
 	"server/settings"
 	"server/torr/storage/torrstor"
 	"server/torr/utils"
+	utils2 "server/utils"
 	"server/version"
 )
 
@@ -24,6 +29,8 @@ type BTServer struct {
 
 	storage *torrstor.Storage
 
+	peerDialer proxy.ContextDialer
+
 	torrents map[metainfo.Hash]*Torrent
 
 	mu sync.Mutex
@@ -59,11 +66,19 @@ func NewBTS() *BTServer {
 func (bt *BTServer) Connect() error {
 	bt.mu.Lock()
 	defer bt.mu.Unlock()
-	var err error
-	bt.configure(context.TODO())
+	err := bt.configure(context.TODO())
+	if err != nil {
+		return err
+	}
 	bt.client, err = torrent.NewClient(bt.config)
 	bt.torrents = make(map[metainfo.Hash]*Torrent)
 	InitApiHelper(bt)
+	if err == nil {
+		if bt.peerDialer != nil {
+			bt.client.AddDialer(torrent.NetworkDialer{Network: "tcp", Dialer: bt.peerDialer})
+		}
+		go loadSeeds()
+	}
 	return err
 }
 
@@ -77,7 +92,7 @@ func (bt *BTServer) Disconnect() {
 	}
 }
 
-func (bt *BTServer) configure(ctx context.Context) {
+func (bt *BTServer) configure(ctx context.Context) error {
 	blocklist, _ := utils.ReadBlockedIP()
 	bt.config = torrent.NewDefaultClientConfig()
 
@@ -109,6 +124,7 @@ func (bt *BTServer) configure(ctx context.Context) {
 	bt.config.ExtendedHandshakeClientVersion = cliVers
 	bt.config.EstablishedConnsPerTorrent = settings.BTsets.ConnectionsLimit
 	bt.config.TotalHalfOpenConns = 500
+	bt.setWebSeedCallbacks()
 	// Encryption/Obfuscation
 	bt.config.HeaderObfuscationPolicy = torrent.HeaderObfuscationPolicy{
 		RequirePreferred: settings.BTsets.ForceEncrypt,
@@ -123,6 +139,23 @@ func (bt *BTServer) configure(ctx context.Context) {
 	if settings.TorAddr != "" {
 		log.Println("Set listen addr", settings.TorAddr)
 		bt.config.SetListenAddr(settings.TorAddr)
+	} else if settings.BTsets.PeersBind != "" {
+		ip, err := utils2.ResolveBind(settings.BTsets.PeersBind)
+		if err != nil {
+			return errors.New("peers listener: " + err.Error())
+		}
+		port := settings.BTsets.PeersListenPort
+		if port <= 0 {
+			port = 32000
+		}
+		addr := net.JoinHostPort(ip, strconv.Itoa(port))
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return errors.New("peers listener: " + err.Error())
+		}
+		l.Close()
+		log.Println("Set listen addr", addr)
+		bt.config.SetListenAddr(addr)
 	} else {
 		if settings.BTsets.PeersListenPort > 0 {
 			log.Println("Set listen port", settings.BTsets.PeersListenPort)
@@ -145,7 +178,9 @@ func (bt *BTServer) configure(ctx context.Context) {
 		}
 	}
 
-	log.Println("Client config:", settings.BTsets)
+	bt.configureProxy()
+
+	log.Println("Client config:", settings.BTsets.Redacted())
 
 	var err error
 
@@ -180,6 +215,60 @@ func (bt *BTServer) configure(ctx context.Context) {
 	if bt.config.PublicIp6 != nil {
 		log.Println("PublicIp6:", bt.config.PublicIp6)
 	}
+	return nil
+}
+
+func (bt *BTServer) configureProxy() {
+	sets := settings.BTsets
+	proxy.Set(sets.ProxyURL, sets.ProxyTrackers, sets.ProxyPeers, sets.ProxyHTTP)
+
+	if u := proxy.URL(proxy.Trackers); u != nil {
+		log.Println("Use trackers proxy", u.Redacted())
+		bt.config.HTTPProxy = http.ProxyURL(u)
+		// udp can't be proxied, don't leak announces
+		bt.config.TrackerListenPacket = func(network, addr string) (net.PacketConn, error) {
+			return nil, errors.New("udp trackers disabled by proxy")
+		}
+	}
+
+	bt.peerDialer = nil
+	if u := proxy.URL(proxy.Peers); u != nil {
+		bt.peerDialer = proxy.Dialer(proxy.Peers)
+		if bt.peerDialer == nil {
+			log.Println("Error peers proxy", u.Redacted(), "is not supported, use direct connections")
+			return
+		}
+		log.Println("Use peers proxy", u.Redacted(), "DHT is disabled")
+		// only outgoing tcp through proxy, dht works over direct udp
+		bt.config.NoDHT = true
+		bt.config.DisableTCP = true
+		bt.config.DisableUTP = true
+		bt.config.DisableWebtorrent = true
+		bt.config.NoDefaultPortForwarding = true
+	}
+}
+
+// DHTEnabled reports if client runs dht, it's off by settings or peers proxy
+func (bt *BTServer) DHTEnabled() bool {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	return bt.config != nil && !bt.config.NoDHT
+}
+
+// ClientState returns listen port and dht nodes of connected client
+func (bt *BTServer) ClientState() (connected bool, port, nodes, goodNodes int) {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	if bt.client == nil {
+		return
+	}
+	for _, s := range bt.client.DhtServers() {
+		if st, ok := s.Stats().(dht.ServerStats); ok {
+			nodes += st.Nodes
+			goodNodes += st.GoodNodes
+		}
+	}
+	return true, bt.client.LocalPort(), nodes, goodNodes
 }
 
 func (bt *BTServer) GetTorrent(hash torrent.InfoHash) *Torrent {
@@ -0,0 +1,142 @@
+package torr
+
+import (
+	"sort"
+
+	"github.com/anacrolix/torrent"
+
+	"server/torr/state"
+)
+
+// ReadySeconds of media buffered ahead is enough to start playback
+const ReadySeconds = 10
+
+// defaultBitrate is used while media bitrate is unknown, bytes/s (8 Mbit/s)
+const defaultBitrate = 1 << 20
+
+// Sources of bitrate in buffer stat
+const (
+	BitrateDuration = "duration" // file size and duration
+	BitrateReader   = "reader"   // observed read speed of player
+	BitrateDefault  = "default"
+)
+
+// SetFileDuration sets media duration in seconds used to estimate bitrate
+func (t *Torrent) SetFileDuration(path string, seconds float64) {
+	t.muTorrent.Lock()
+	defer t.muTorrent.Unlock()
+	if t.durations == nil {
+		t.durations = make(map[string]float64)
+	}
+	t.durations[path] = seconds
+}
+
+// FileBuffer returns buffer health of file, for an opened reader if it exists
+// or from file start before playback
+func (t *Torrent) FileBuffer(id int) *state.BufferStat {
+	t.muTorrent.Lock()
+	defer t.muTorrent.Unlock()
+	if t.Torrent == nil || t.Torrent.Info() == nil {
+		return nil
+	}
+	for _, b := range t.bufferStats() {
+		if b.Id == id {
+			return b
+		}
+	}
+	files := t.Files()
+	for path, fid := range fileIDs(files) {
+		if fid != id {
+			continue
+		}
+		for _, file := range files {
+			if file.Path() == path {
+				return t.bufferStat(file, id, 0, 0, t.DownloadSpeed)
+			}
+		}
+	}
+	return nil
+}
+
+// bufferStats returns buffer health of opened readers, muTorrent must be locked
+func (t *Torrent) bufferStats() []*state.BufferStat {
+	readers := t.cache.GetReaders()
+	if len(readers) == 0 || t.Torrent.Info() == nil {
+		return nil
+	}
+	ids := fileIDs(t.Files())
+	speed := t.DownloadSpeed / float64(len(readers))
+	list := make([]*state.BufferStat, 0, len(readers))
+	for _, r := range readers {
+		file := r.File()
+		list = append(list, t.bufferStat(file, ids[file.Path()], r.Offset(), r.ReadRate(), speed))
+	}
+	return list
+}
+
+func (t *Torrent) bufferStat(file *torrent.File, id int, offset int64, readRate, speed float64) *state.BufferStat {
+	b := &state.BufferStat{
+		Id:            id,
+		Path:          file.Path(),
+		Offset:        offset,
+		BufferedBytes: bufferedAhead(file, offset),
+		DownloadSpeed: speed,
+		StallIn:       -1,
+	}
+	if dur := t.durations[file.Path()]; dur > 0 {
+		b.Bitrate = float64(file.Length()) / dur
+		b.BitrateSource = BitrateDuration
+	} else if readRate > 0 {
+		b.Bitrate = readRate
+		b.BitrateSource = BitrateReader
+	} else {
+		b.Bitrate = defaultBitrate
+		b.BitrateSource = BitrateDefault
+	}
+	b.BufferedSeconds = float64(b.BufferedBytes) / b.Bitrate
+
+	complete := offset+b.BufferedBytes >= file.Length()
+	if !complete && speed < b.Bitrate {
+		// buffer drains with the difference of rates
+		b.StallIn = float64(b.BufferedBytes) / (b.Bitrate - speed)
+	}
+	b.Ready = complete || b.BufferedSeconds >= ReadySeconds ||
+		(b.StallIn < 0 && b.BufferedSeconds >= ReadySeconds/2)
+	return b
+}
+
+// bufferedAhead returns size of completed data from offset to first missing piece
+func bufferedAhead(file *torrent.File, offset int64) int64 {
+	t := file.Torrent()
+	pieceLength := t.Info().PieceLength
+	if pieceLength == 0 || offset >= file.Length() {
+		return 0
+	}
+	start := file.Offset() + offset
+	end := file.Offset() + file.Length()
+	pos := start
+	for i := int(start / pieceLength); pos < end; i++ {
+		if !t.PieceState(i).Complete {
+			break
+		}
+		pos = int64(i+1) * pieceLength
+	}
+	if pos > end {
+		pos = end
+	}
+	return pos - start
+}
+
+// fileIDs returns web ids of files, it is index in sorted by path list
+func fileIDs(files []*torrent.File) map[string]int {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path()
+	}
+	sort.Strings(paths)
+	ids := make(map[string]int, len(paths))
+	for i, p := range paths {
+		ids[p] = i + 1 // in web id 0 is undefined
+	}
+	return ids
+}
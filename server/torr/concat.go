@@ -0,0 +1,247 @@
+package torr
+
+import (
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anacrolix/torrent"
+
+	"server/torr/storage/torrstor"
+	utils2 "server/utils"
+)
+
+var (
+	// movie.mkv.001, movie.mkv.002
+	reNumberedPart = regexp.MustCompile(`^(.+)\.(\d{3})$`)
+	// Movie CD1.avi, Movie.cd2.avi
+	reCDPart = regexp.MustCompile(`(?i)^(.*?)[ ._-]*\bcd[ ._-]*(\d{1,2})(\.[^.]+)$`)
+	// 00001.ts, video_002.ts
+	reTSPart = regexp.MustCompile(`(?i)^(.*?[ ._-])?(\d+)\.ts$`)
+)
+
+// joinedFile is split file of torrent played as one
+type joinedFile struct {
+	Path  string
+	Parts []string
+}
+
+// joinFiles finds split files by names of parts, parts must be numbered
+// one after another, numbered and ts parts must be of same size except the
+// last one, so episodes aren't joined
+func joinFiles(paths []string, sizes map[string]int64) []*joinedFile {
+	type part struct {
+		num  int
+		path string
+	}
+	groups := make(map[string][]part)
+	names := make(map[string]string)
+	exists := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		exists[p] = true
+	}
+	for _, p := range paths {
+		dir, file := path.Split(p)
+		var key, name, num string
+		if m := reNumberedPart.FindStringSubmatch(file); m != nil {
+			key, name, num = "n:"+dir+m[1], dir+m[1], m[2]
+		} else if m := reCDPart.FindStringSubmatch(file); m != nil && utils2.GetMimeType(file) != "*/*" {
+			key, name, num = "cd:"+dir+strings.ToLower(m[1]+m[3]), dir+joinedName(m[1], dir, m[3]), m[2]
+		} else if m := reTSPart.FindStringSubmatch(file); m != nil {
+			key, name, num = "ts:"+dir+strings.ToLower(m[1]), dir+joinedName(m[1], dir, ".ts"), m[2]
+		} else {
+			continue
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			continue
+		}
+		groups[key] = append(groups[key], part{n, p})
+		names[key] = name
+	}
+	var ret []*joinedFile
+	for key, parts := range groups {
+		if len(parts) < 2 || exists[names[key]] {
+			continue
+		}
+		sort.Slice(parts, func(i, j int) bool {
+			return parts[i].num < parts[j].num
+		})
+		// ts sequences may start from any number
+		if parts[0].num > 1 && !strings.HasPrefix(key, "ts:") {
+			continue
+		}
+		jf := &joinedFile{Path: names[key]}
+		for i, pt := range parts {
+			if pt.num != parts[0].num+i {
+				jf = nil
+				break
+			}
+			jf.Parts = append(jf.Parts, pt.path)
+		}
+		if jf != nil && !strings.HasPrefix(key, "cd:") && !sameSize(jf.Parts, sizes) {
+			jf = nil
+		}
+		if jf != nil {
+			ret = append(ret, jf)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+	return ret
+}
+
+// sameSize checks that parts are of one size, the last one may be shorter
+func sameSize(parts []string, sizes map[string]int64) bool {
+	size := sizes[parts[0]]
+	for i, p := range parts {
+		if sizes[p] > size || sizes[p] < size && i < len(parts)-1 {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Torrent) joinFiles(files []*torrent.File) {
+	paths := make([]string, 0, len(files))
+	sizes := make(map[string]int64, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path())
+		sizes[f.Path()] = f.Length()
+	}
+	joined := joinFiles(paths, sizes)
+	t.muTorrent.Lock()
+	t.joined = joined
+	t.muTorrent.Unlock()
+}
+
+// joinedName is name of parts without number, name of directory if parts
+// are only numbered
+func joinedName(prefix, dir, ext string) string {
+	prefix = strings.TrimRight(prefix, " ._-")
+	if prefix == "" {
+		prefix = path.Base(strings.TrimSuffix(dir, "/"))
+		if prefix == "." || prefix == "/" {
+			prefix = "joined"
+		}
+	}
+	return prefix + ext
+}
+
+// concatReader reads files of torrent one after another as one file,
+// reader of part is opened when read gets to it, next part is opened
+// before that to download its start with priority
+type concatReader struct {
+	t      *Torrent
+	parts  []*torrent.File
+	starts []int64
+	size   int64
+	pos    int64
+
+	part   int
+	reader *torrstor.Reader
+	next   *torrstor.Reader
+}
+
+func newConcatReader(t *Torrent, parts []*torrent.File) *concatReader {
+	c := &concatReader{t: t, parts: parts, part: -1}
+	for _, f := range parts {
+		c.starts = append(c.starts, c.size)
+		c.size += f.Length()
+	}
+	return c
+}
+
+func (c *concatReader) Read(p []byte) (int, error) {
+	if c.pos >= c.size {
+		return 0, io.EOF
+	}
+	part := len(c.parts) - 1
+	for part > 0 && c.starts[part] > c.pos {
+		part--
+	}
+	if part != c.part {
+		c.closePart()
+		if c.next != nil && c.next.File() == c.parts[part] {
+			c.reader, c.next = c.next, nil
+		} else {
+			c.reader = c.t.NewReader(c.parts[part])
+		}
+		if c.reader == nil {
+			return 0, errClosed
+		}
+		c.part = part
+	}
+	off := c.pos - c.starts[part]
+	if c.reader.Offset() != off {
+		if _, err := c.reader.Seek(off, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+	left := c.parts[part].Length() - off
+	c.prefetch(part+1, left)
+	// reader does not stop at the end of file
+	if int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := c.reader.Read(p)
+	c.pos += int64(n)
+	if err == io.EOF && c.pos < c.size {
+		err = nil
+	}
+	return n, err
+}
+
+// prefetch opens reader at start of next part when end of current part is
+// in cache window, so both ranges get priority
+func (c *concatReader) prefetch(part int, left int64) {
+	if part >= len(c.parts) || left > c.reader.AheadSize() {
+		if c.next != nil {
+			c.t.CloseReader(c.next)
+			c.next = nil
+		}
+		return
+	}
+	if c.next == nil {
+		if c.next = c.t.NewReader(c.parts[part]); c.next == nil {
+			return
+		}
+	}
+	// seek keeps reader in use, unused readers lose priority
+	c.next.Seek(0, io.SeekStart)
+}
+
+func (c *concatReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += c.pos
+	case io.SeekEnd:
+		offset += c.size
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	c.pos = offset
+	return offset, nil
+}
+
+func (c *concatReader) closePart() {
+	if c.reader != nil {
+		c.t.CloseReader(c.reader)
+		c.reader = nil
+		c.part = -1
+	}
+}
+
+func (c *concatReader) Close() {
+	c.closePart()
+	if c.next != nil {
+		c.t.CloseReader(c.next)
+		c.next = nil
+	}
+}
@@ -0,0 +1,115 @@
+package torr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	sets "server/settings"
+	"server/torr/state"
+)
+
+// ErrOutOfRoot is returned for path out of content root or without root
+var ErrOutOfRoot = errors.New("path is out of content root")
+
+// maxCreateJobs is count of last jobs kept for list
+const maxCreateJobs = 100
+
+var (
+	createJobs   []*state.CreateJobStatus
+	createLastId int
+	muCreate     sync.Mutex
+)
+
+// StartCreate checks that path is in content root and creates torrent in
+// background, onDone is called with hash of created torrent
+func StartCreate(path string, pieceLength int64, trackers []string, comment, title string, onDone func(hash string)) (*state.CreateJobStatus, error) {
+	if sets.ReadOnly {
+		return nil, errors.New("db in read-only mode")
+	}
+	path, err := contentPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	muCreate.Lock()
+	createLastId++
+	job := &state.CreateJobStatus{
+		Id:          createLastId,
+		Path:        path,
+		Title:       title,
+		State:       "hashing",
+		TorrentPath: path + ".torrent",
+		Added:       time.Now().Unix(),
+	}
+	createJobs = append(createJobs, job)
+	if len(createJobs) > maxCreateJobs {
+		createJobs = createJobs[len(createJobs)-maxCreateJobs:]
+	}
+	ret := *job
+	muCreate.Unlock()
+
+	go func() {
+		tor, mi, err := CreateTorrent(path, pieceLength, trackers, comment, title)
+		muCreate.Lock()
+		defer muCreate.Unlock()
+		if err != nil {
+			torrLog.Error("error create torrent", "path", path, "err", err)
+			job.State = "failed"
+			job.Error = err.Error()
+			return
+		}
+		info, _ := mi.UnmarshalInfo()
+		hash := mi.HashInfoBytes()
+		job.State = "done"
+		job.Hash = hash.HexString()
+		job.Magnet = mi.Magnet(&hash, &info).String()
+		job.Title = tor.Title
+		if onDone != nil {
+			onDone(job.Hash)
+		}
+	}()
+	return &ret, nil
+}
+
+// ListCreateJobs returns last created torrents
+func ListCreateJobs() []*state.CreateJobStatus {
+	muCreate.Lock()
+	defer muCreate.Unlock()
+	list := make([]*state.CreateJobStatus, 0, len(createJobs))
+	for _, job := range createJobs {
+		j := *job
+		list = append(list, &j)
+	}
+	return list
+}
+
+// contentPath resolves path relative to content root and rejects paths
+// out of it, symlinks are followed
+func contentPath(path string) (string, error) {
+	if sets.ContentRoot == "" {
+		return "", ErrOutOfRoot
+	}
+	root, err := filepath.Abs(sets.ContentRoot)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", ErrOutOfRoot
+	}
+	return path, nil
+}
@@ -19,8 +19,22 @@ type tsFiles struct {
 
 func AddTorrentDB(torr *Torrent) {
 	t := new(settings.TorrentDB)
-	t.TorrentSpec = torr.TorrentSpec
+	spec := *torr.TorrentSpec
+	spec.Storage = nil
+	if len(spec.InfoBytes) == 0 {
+		// keep info for offline .torrent export
+		spec.InfoBytes = torr.infoBytes()
+	}
+	t.TorrentSpec = &spec
 	t.Title = torr.Title
+	t.SeedPath = torr.SeedPath
+	t.SelectOnly = torr.SelectOnly
+	t.Category = torr.Category
+	if t.Category == "" {
+		if old := settings.GetTorrent(spec.InfoHash); old != nil {
+			t.Category = old.Category
+		}
+	}
 	if torr.Data == "" {
 		files := new(tsFiles)
 		files.TorrServer.Files = torr.Status().FileStats
@@ -42,21 +56,11 @@ func AddTorrentDB(torr *Torrent) {
 }
 
 func GetTorrentDB(hash metainfo.Hash) *Torrent {
-	list := settings.ListTorrent()
-	for _, db := range list {
-		if hash == db.InfoHash {
-			torr := new(Torrent)
-			torr.TorrentSpec = db.TorrentSpec
-			torr.Title = db.Title
-			torr.Poster = db.Poster
-			torr.Timestamp = db.Timestamp
-			torr.Size = db.Size
-			torr.Data = db.Data
-			torr.Stat = state.TorrentInDB
-			return torr
-		}
+	db := settings.GetTorrent(hash)
+	if db == nil {
+		return nil
 	}
-	return nil
+	return fromTorrentDB(db)
 }
 
 func RemTorrentDB(hash metainfo.Hash) {
@@ -67,15 +71,23 @@ func ListTorrentsDB() map[metainfo.Hash]*Torrent {
 	ret := make(map[metainfo.Hash]*Torrent)
 	list := settings.ListTorrent()
 	for _, db := range list {
-		torr := new(Torrent)
-		torr.TorrentSpec = db.TorrentSpec
-		torr.Title = db.Title
-		torr.Poster = db.Poster
-		torr.Timestamp = db.Timestamp
-		torr.Size = db.Size
-		torr.Data = db.Data
-		torr.Stat = state.TorrentInDB
+		torr := fromTorrentDB(db)
 		ret[torr.TorrentSpec.InfoHash] = torr
 	}
 	return ret
 }
+
+func fromTorrentDB(db *settings.TorrentDB) *Torrent {
+	torr := new(Torrent)
+	torr.TorrentSpec = db.TorrentSpec
+	torr.Title = db.Title
+	torr.Poster = db.Poster
+	torr.Timestamp = db.Timestamp
+	torr.Size = db.Size
+	torr.Data = db.Data
+	torr.Category = db.Category
+	torr.SeedPath = db.SeedPath
+	torr.SelectOnly = db.SelectOnly
+	torr.Stat = state.TorrentInDB
+	return torr
+}
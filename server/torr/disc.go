@@ -0,0 +1,66 @@
+package torr
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/anacrolix/torrent"
+
+	"server/disc"
+	"server/probe"
+	"server/settings"
+)
+
+// scanDiscs finds main titles of DVD and Blu-ray structures in torrent,
+// saved titles are loaded from db
+func (t *Torrent) scanDiscs(hash string, files []*torrent.File) {
+	defer recoverScan("disc scan", hash)
+	paths := make([]string, 0, len(files))
+	byPath := make(map[string]*torrent.File, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path())
+		byPath[f.Path()] = f
+	}
+	for _, d := range disc.Find(paths) {
+		var titles []*disc.Title
+		if !settings.GetDiscTitles(hash, d.Root, &titles) {
+			var err error
+			titles, err = d.Titles(func(path string) ([]byte, error) {
+				return t.readInfoFile(byPath[path])
+			})
+			if err != nil {
+				probeLog.Debug("read disc titles failed", "hash", hash, "disc", d.Root, "error", err)
+				continue
+			}
+			settings.SetDiscTitles(hash, d.Root, titles)
+		}
+		if len(titles) == 0 {
+			continue
+		}
+		probeLog.Debug("disc titles", "hash", hash, "disc", d.Root, "type", d.Type, "titles", len(titles))
+		for _, title := range titles {
+			if title.Duration > 0 {
+				t.setMedia(disc.TitlePath(d.Root, title), &probe.Info{Format: d.Type, Duration: title.Duration})
+			}
+		}
+		t.muTorrent.Lock()
+		if t.titles == nil {
+			t.titles = make(map[string][]*disc.Title)
+		}
+		t.titles[d.Root] = titles
+		t.muTorrent.Unlock()
+	}
+}
+
+// readInfoFile reads small file of disc structure
+func (t *Torrent) readInfoFile(file *torrent.File) ([]byte, error) {
+	if file.Length() > disc.MaxInfoFile {
+		return nil, fmt.Errorf("file is too big: %v", file.Length())
+	}
+	var data []byte
+	err := t.scanFile(file, func(r io.ReadSeeker) (err error) {
+		data, err = io.ReadAll(r)
+		return
+	})
+	return data, err
+}
@@ -0,0 +1,81 @@
+package torr
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/anacrolix/torrent/metainfo"
+
+	"server/version"
+)
+
+// BuildMetaInfo rebuilds .torrent from info bytes, trackers and web seeds
+func (t *Torrent) BuildMetaInfo() (*metainfo.MetaInfo, error) {
+	if t.TorrentSpec == nil {
+		return nil, errors.New("torrent spec is empty")
+	}
+	mi := &metainfo.MetaInfo{
+		InfoBytes: t.infoBytes(),
+		UrlList:   t.TorrentSpec.Webseeds,
+	}
+	if len(mi.InfoBytes) == 0 {
+		return nil, errors.New("torrent info not loaded")
+	}
+	if metainfo.HashBytes(mi.InfoBytes) != t.TorrentSpec.InfoHash {
+		return nil, errors.New("torrent info hash mismatch")
+	}
+	for _, tier := range t.TorrentSpec.Trackers {
+		if len(tier) > 0 {
+			mi.AnnounceList = append(mi.AnnounceList, tier)
+		}
+	}
+	if len(mi.AnnounceList) > 0 {
+		mi.Announce = mi.AnnounceList[0][0]
+	}
+	mi.SetDefaults()
+	mi.CreatedBy = "TorrServer/" + version.Version
+	return mi, nil
+}
+
+func (t *Torrent) infoBytes() []byte {
+	t.muTorrent.Lock()
+	defer t.muTorrent.Unlock()
+	if t.Torrent != nil && t.Torrent.Info() != nil {
+		return t.Torrent.Metainfo().InfoBytes
+	}
+	return t.TorrentSpec.InfoBytes
+}
+
+// MagnetURI returns magnet with trackers, display name, web seeds, peers and
+// selected files
+func (t *Torrent) MagnetURI() string {
+	if t.TorrentSpec == nil {
+		return ""
+	}
+	mi := metainfo.MetaInfo{AnnounceList: t.TorrentSpec.Trackers}
+	mag := metainfo.Magnet{
+		InfoHash:    t.TorrentSpec.InfoHash,
+		Trackers:    mi.UpvertedAnnounceList().DistinctValues(),
+		DisplayName: t.Title,
+	}
+	if mag.DisplayName == "" {
+		mag.DisplayName = t.TorrentSpec.DisplayName
+	}
+	mag.Params = make(url.Values)
+	for _, ws := range t.TorrentSpec.Webseeds {
+		mag.Params.Add("ws", ws)
+	}
+	for _, pe := range t.TorrentSpec.PeerAddrs {
+		mag.Params.Add("x.pe", pe)
+	}
+	if len(t.SelectOnly) > 0 {
+		so := make([]string, len(t.SelectOnly))
+		for i, id := range t.SelectOnly {
+			so[i] = strconv.Itoa(id)
+		}
+		mag.Params.Set("so", strings.Join(so, ","))
+	}
+	return mag.String()
+}
@@ -0,0 +1,327 @@
+package torr
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+
+	sets "server/settings"
+	"server/torr/state"
+)
+
+type metaJob struct {
+	// spec as torrent was added, each attempt adds its copy
+	spec   *torrent.TorrentSpec
+	title  string
+	poster string
+	data   string
+	onInfo func(*Torrent)
+	onFail func(reason string)
+
+	state    state.MetaState
+	attempts int
+	err      string
+	added    time.Time
+	nextTry  time.Time
+	torr     *Torrent
+	cancel   chan struct{}
+}
+
+type metaQueue struct {
+	jobs    map[metainfo.Hash]*metaJob
+	running int
+	mu      sync.Mutex
+}
+
+var metaQ = &metaQueue{jobs: make(map[metainfo.Hash]*metaJob)}
+
+func metaTimeout() time.Duration {
+	if sets.BTsets == nil || sets.BTsets.MetaTimeout <= 0 {
+		return time.Minute * 5
+	}
+	return time.Second * time.Duration(sets.BTsets.MetaTimeout)
+}
+
+// ResolveMeta queues waiting of torrent info, onInfo called when info received,
+// onFail when all retries failed or job canceled. If the torrent is already in
+// the queue, callbacks are added to its job and failed job is restarted.
+// Returns false if torrent has no spec, callbacks are not called then.
+func ResolveMeta(tor *Torrent, onInfo func(*Torrent), onFail func(reason string)) bool {
+	if tor == nil || tor.TorrentSpec == nil {
+		return false
+	}
+	if tor.Torrent != nil && tor.Torrent.Info() != nil {
+		if !tor.GotInfo() {
+			if onFail != nil {
+				onFail("torrent closed")
+			}
+		} else if onInfo != nil {
+			onInfo(tor)
+		}
+		return true
+	}
+	metaQ.mu.Lock()
+	hash := tor.TorrentSpec.InfoHash
+	if job, ok := metaQ.jobs[hash]; ok {
+		job.onInfo = chainInfo(job.onInfo, onInfo)
+		job.onFail = chainFail(job.onFail, onFail)
+		if job.state == state.MetaFailed {
+			job.state = state.MetaPending
+			job.attempts = 0
+			job.err = ""
+			job.nextTry = time.Time{}
+		}
+		metaQ.mu.Unlock()
+		metaQ.schedule()
+		return true
+	}
+	spec := tor.addSpec
+	if spec == nil {
+		spec = tor.TorrentSpec
+	}
+	metaQ.jobs[hash] = &metaJob{
+		spec:   cloneSpec(spec),
+		title:  tor.Title,
+		poster: tor.Poster,
+		data:   tor.Data,
+		onInfo: onInfo,
+		onFail: onFail,
+		state:  state.MetaPending,
+		added:  time.Now(),
+		torr:   tor,
+	}
+	metaQ.mu.Unlock()
+	metaQ.schedule()
+	return true
+}
+
+func ListMetaJobs() []*state.MetaJobStatus {
+	metaQ.mu.Lock()
+	defer metaQ.mu.Unlock()
+	list := make([]*state.MetaJobStatus, 0, len(metaQ.jobs))
+	for hash, job := range metaQ.jobs {
+		st := &state.MetaJobStatus{
+			Hash:        hash.HexString(),
+			Title:       job.title,
+			State:       job.state,
+			StateString: job.state.String(),
+			Attempts:    job.attempts,
+			Error:       job.err,
+			Added:       job.added.Unix(),
+		}
+		if st.Title == "" {
+			st.Title = job.spec.DisplayName
+		}
+		if job.state == state.MetaPending && !job.nextTry.IsZero() {
+			st.NextTry = job.nextTry.Unix()
+		}
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Added < list[j].Added
+	})
+	return list
+}
+
+func RetryMeta(hashHex string) error {
+	hash := metainfo.NewHashFromHex(hashHex)
+	metaQ.mu.Lock()
+	job, ok := metaQ.jobs[hash]
+	if !ok {
+		metaQ.mu.Unlock()
+		return errors.New("metadata job not found")
+	}
+	if job.state == state.MetaResolving {
+		metaQ.mu.Unlock()
+		return errors.New("metadata already resolving")
+	}
+	job.state = state.MetaPending
+	job.attempts = 0
+	job.err = ""
+	job.nextTry = time.Time{}
+	metaQ.mu.Unlock()
+	metaQ.schedule()
+	return nil
+}
+
+func CancelMeta(hashHex string) error {
+	hash := metainfo.NewHashFromHex(hashHex)
+	metaQ.mu.Lock()
+	job, ok := metaQ.jobs[hash]
+	if !ok {
+		metaQ.mu.Unlock()
+		return errors.New("metadata job not found")
+	}
+	delete(metaQ.jobs, hash)
+	if job.cancel != nil {
+		close(job.cancel)
+		job.cancel = nil
+	}
+	tor := job.torr
+	_, onFail := job.takeCallbacks()
+	metaQ.mu.Unlock()
+	if tor != nil && tor.Stat != state.TorrentClosed {
+		tor.Close()
+	}
+	torrLog.Info("cancel metadata resolving", "hash", hashHex)
+	if onFail != nil {
+		go onFail("canceled")
+	}
+	return nil
+}
+
+func (q *metaQueue) schedule() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	limit := 5
+	if sets.BTsets != nil && sets.BTsets.MetaResolveLimit > 0 {
+		limit = sets.BTsets.MetaResolveLimit
+	}
+	now := time.Now()
+	var pending []*metaJob
+	for _, job := range q.jobs {
+		if job.state == state.MetaPending && !job.nextTry.After(now) {
+			pending = append(pending, job)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].added.Before(pending[j].added)
+	})
+	for _, job := range pending {
+		if q.running >= limit {
+			return
+		}
+		q.running++
+		job.state = state.MetaResolving
+		job.attempts++
+		job.cancel = make(chan struct{})
+		go q.resolve(job, job.cancel)
+	}
+}
+
+func (q *metaQueue) resolve(job *metaJob, cancel chan struct{}) {
+	defer func() {
+		q.mu.Lock()
+		q.running--
+		q.mu.Unlock()
+		q.schedule()
+	}()
+
+	tor := job.torr
+	if tor == nil || tor.Stat == state.TorrentClosed {
+		var err error
+		// NewTorrent adds trackers to spec, so attempt gets its copy
+		tor, err = AddTorrent(cloneSpec(job.spec), job.title, job.poster, job.data)
+		if err != nil {
+			q.failed(job, tor, err.Error())
+			return
+		}
+		q.mu.Lock()
+		job.torr = tor
+		q.mu.Unlock()
+	}
+
+	tor.Stat = state.TorrentGettingInfo
+	if !tor.waitInfo(metaTimeout(), cancel) {
+		select {
+		case <-cancel:
+			return
+		default:
+		}
+		q.failed(job, tor, "timeout connection get torrent info")
+		return
+	}
+	tor.Stat = state.TorrentWorking
+	tor.AddExpiredTime(time.Minute * 5)
+
+	q.mu.Lock()
+	if q.jobs[job.spec.InfoHash] == job {
+		delete(q.jobs, job.spec.InfoHash)
+	}
+	onInfo, _ := job.takeCallbacks()
+	q.mu.Unlock()
+
+	if onInfo != nil {
+		onInfo(tor)
+	}
+}
+
+func (q *metaQueue) failed(job *metaJob, tor *Torrent, reason string) {
+	if tor != nil {
+		tor.Close()
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.jobs[job.spec.InfoHash] != job {
+		return
+	}
+	job.torr = nil
+	job.err = reason
+	retries := 3
+	if sets.BTsets != nil {
+		retries = sets.BTsets.MetaRetries
+	}
+	if job.attempts > retries {
+		job.state = state.MetaFailed
+		torrLog.Error("error get torrent info", "hash", job.spec.InfoHash.HexString(), "reason", reason)
+		if _, onFail := job.takeCallbacks(); onFail != nil {
+			go onFail(reason)
+		}
+		return
+	}
+	// backoff 30s, 1m, 2m ... up to 30m
+	backoff := time.Second * 30 << uint(job.attempts-1)
+	if backoff > time.Minute*30 || backoff <= 0 {
+		backoff = time.Minute * 30
+	}
+	job.state = state.MetaPending
+	job.nextTry = time.Now().Add(backoff)
+	torrLog.Warn("retry get torrent info", "hash", job.spec.InfoHash.HexString(), "in", backoff, "reason", reason)
+	time.AfterFunc(backoff, q.schedule)
+}
+
+func chainInfo(first, next func(*Torrent)) func(*Torrent) {
+	if first == nil || next == nil {
+		if first == nil {
+			return next
+		}
+		return first
+	}
+	return func(tor *Torrent) {
+		first(tor)
+		next(tor)
+	}
+}
+
+func chainFail(first, next func(reason string)) func(reason string) {
+	if first == nil || next == nil {
+		if first == nil {
+			return next
+		}
+		return first
+	}
+	return func(reason string) {
+		first(reason)
+		next(reason)
+	}
+}
+
+// cloneSpec copies spec with its lists of trackers and web seeds
+func cloneSpec(spec *torrent.TorrentSpec) *torrent.TorrentSpec {
+	c := *spec
+	c.Trackers = append([][]string(nil), spec.Trackers...)
+	c.Webseeds = append([]string(nil), spec.Webseeds...)
+	return &c
+}
+
+// takeCallbacks returns callbacks and clears them, so finished or failed job
+// does not call them again on retry or cancel, q.mu must be locked
+func (job *metaJob) takeCallbacks() (func(*Torrent), func(reason string)) {
+	onInfo, onFail := job.onInfo, job.onFail
+	job.onInfo, job.onFail = nil, nil
+	return onInfo, onFail
+}
@@ -0,0 +1,82 @@
+package torr
+
+import (
+	"sort"
+
+	"server/metrics"
+	"server/torr/state"
+)
+
+type torrMetrics struct {
+	st        *state.TorrentStatus
+	filled    int64
+	capacity  int64
+	evictions int64
+	readers   int
+}
+
+type torrMetric struct {
+	name, help string
+	counter    bool
+	value      func(m *torrMetrics) float64
+}
+
+var torrMetricList = []torrMetric{
+	{"download_speed_bytes", "Download speed in bytes/s.", false, func(m *torrMetrics) float64 { return m.st.DownloadSpeed }},
+	{"upload_speed_bytes", "Upload speed in bytes/s.", false, func(m *torrMetrics) float64 { return m.st.UploadSpeed }},
+	{"peers_total", "Known peers.", false, func(m *torrMetrics) float64 { return float64(m.st.TotalPeers) }},
+	{"peers_active", "Connected peers.", false, func(m *torrMetrics) float64 { return float64(m.st.ActivePeers) }},
+	{"seeders_connected", "Connected seeders.", false, func(m *torrMetrics) float64 { return float64(m.st.ConnectedSeeders) }},
+	{"read_bytes_total", "Useful data got from peers.", true, func(m *torrMetrics) float64 { return float64(m.st.BytesReadUsefulData) }},
+	{"written_bytes_total", "Data sent to peers.", true, func(m *torrMetrics) float64 { return float64(m.st.BytesWrittenData) }},
+	{"loaded_bytes", "Completed data of torrent.", false, func(m *torrMetrics) float64 { return float64(m.st.LoadedSize) }},
+	{"size_bytes", "Size of torrent.", false, func(m *torrMetrics) float64 { return float64(m.st.TorrentSize) }},
+	{"cache_filled_bytes", "Data in cache.", false, func(m *torrMetrics) float64 { return float64(m.filled) }},
+	{"cache_capacity_bytes", "Capacity of cache.", false, func(m *torrMetrics) float64 { return float64(m.capacity) }},
+	{"cache_evictions_total", "Pieces removed from cache to free space.", true, func(m *torrMetrics) float64 { return float64(m.evictions) }},
+	{"readers", "Opened readers.", false, func(m *torrMetrics) float64 { return float64(m.readers) }},
+}
+
+// WriteMetrics writes totals and per torrent metrics of active torrents
+func WriteMetrics(w *metrics.Writer) {
+	if bts == nil {
+		return
+	}
+	var list []*torrMetrics
+	for _, t := range bts.ListTorrents() {
+		m := &torrMetrics{st: t.Status()}
+		if c := t.GetCache(); c != nil {
+			cs := c.GetState()
+			m.filled = cs.Filled
+			m.capacity = cs.Capacity
+			m.evictions = c.Evictions()
+			m.readers = c.Readers()
+		}
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].st.Hash < list[j].st.Hash
+	})
+
+	w.Gauge("torrserver_torrents_active", "Torrents in torrent client.", float64(len(list)))
+	for _, tm := range torrMetricList {
+		if tm.counter {
+			continue
+		}
+		sum := 0.0
+		for _, m := range list {
+			sum += tm.value(m)
+		}
+		w.Gauge("torrserver_"+tm.name, tm.help+" Sum of torrents.", sum)
+	}
+	for _, tm := range torrMetricList {
+		for _, m := range list {
+			name := "torrserver_torrent_" + tm.name
+			if tm.counter {
+				w.Counter(name, tm.help, tm.value(m), "hash", m.st.Hash, "name", m.st.Title)
+			} else {
+				w.Gauge(name, tm.help, tm.value(m), "hash", m.st.Hash, "name", m.st.Title)
+			}
+		}
+	}
+}
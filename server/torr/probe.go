@@ -0,0 +1,182 @@
+package torr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/anacrolix/torrent"
+
+	"server/archive"
+	"server/log"
+	"server/probe"
+	"server/settings"
+	"server/torr/state"
+	"server/torr/storage/torrstor"
+	utils2 "server/utils"
+)
+
+var probeLog = log.Component("probe")
+
+// probeSem lets one file be probed at a time, probing downloads headers
+var probeSem = make(chan struct{}, 1)
+
+const (
+	probeTimeout  = time.Minute
+	maxProbeFiles = 50
+)
+
+// probeFiles scans archives and discs and loads saved media info of audio
+// and video files, only files selected by so= are probed now, others are
+// probed after their first stream
+func (t *Torrent) probeFiles() {
+	hash := t.Hash().HexString()
+	defer recoverScan("probe", hash)
+	files := t.sortedFiles()
+	for _, file := range files {
+		if archive.IsZip(file.Path()) {
+			t.scanArchive(hash, file)
+		}
+	}
+	t.scanDiscs(hash, files)
+	selected := t.selectedFiles(t.Files())
+	count := 0
+	for _, file := range files {
+		path := file.Path()
+		if utils2.GetMimeType(path) == "*/*" {
+			continue
+		}
+		info := new(probe.Info)
+		if settings.GetMediaInfo(hash, path, info) {
+			t.setMedia(path, info)
+			continue
+		}
+		if !selected[file] {
+			continue
+		}
+		if count++; count > maxProbeFiles {
+			continue
+		}
+		if t.probeFile(hash, file) == errClosed {
+			return
+		}
+	}
+}
+
+// probeStreamed probes file after its stream if it has no media info yet
+func (t *Torrent) probeStreamed(file *torrent.File) {
+	path := file.Path()
+	if utils2.GetMimeType(path) == "*/*" {
+		return
+	}
+	t.muTorrent.Lock()
+	// probed files without media failed
+	done := t.media[path] != nil || t.probed[path]
+	t.muTorrent.Unlock()
+	if done {
+		return
+	}
+	hash := t.Hash().HexString()
+	defer recoverScan("probe", hash)
+	t.probeFile(hash, file)
+}
+
+// probeFile reads media info of file when buffer is not used by streams
+// or preload, headers are read from cache after stream
+func (t *Torrent) probeFile(hash string, file *torrent.File) error {
+	path := file.Path()
+	if t.busy() {
+		probeLog.Debug("probe skipped, buffer is busy", "hash", hash, "file", path)
+		return nil
+	}
+	t.muTorrent.Lock()
+	if t.probed == nil {
+		t.probed = make(map[string]bool)
+	}
+	t.probed[path] = true
+	t.muTorrent.Unlock()
+	var info *probe.Info
+	err := t.scanFile(file, func(r io.ReadSeeker) (err error) {
+		info, err = probe.Probe(r, file.Length())
+		return
+	})
+	if err != nil {
+		if err != errClosed {
+			probeLog.Debug("probe failed", "hash", hash, "file", path, "error", err)
+		}
+		return err
+	}
+	probeLog.Debug("probed", "hash", hash, "file", path, "format", info.Format, "duration", info.Duration)
+	settings.SetMediaInfo(hash, path, info)
+	t.setMedia(path, info)
+	return nil
+}
+
+// busy reports if torrent preloads or streams
+func (t *Torrent) busy() bool {
+	return t.Stat == state.TorrentPreload || t.cache.Readers() > 0
+}
+
+func (t *Torrent) setMedia(path string, info *probe.Info) {
+	t.muTorrent.Lock()
+	defer t.muTorrent.Unlock()
+	if t.media == nil {
+		t.media = make(map[string]*probe.Info)
+	}
+	t.media[path] = info
+	if info.Duration > 0 {
+		if t.durations == nil {
+			t.durations = make(map[string]float64)
+		}
+		t.durations[path] = info.Duration
+	}
+}
+
+var errClosed = errors.New("torrent closed")
+
+// scanFile runs fn with reader of file for scans in background, files are
+// read one at a time and reading stops after probeTimeout
+func (t *Torrent) scanFile(file *torrent.File, fn func(r io.ReadSeeker) error) error {
+	select {
+	case probeSem <- struct{}{}:
+	case <-t.closed:
+		return errClosed
+	}
+	defer func() { <-probeSem }()
+	reader := t.NewReader(file)
+	if reader == nil {
+		return errClosed
+	}
+	// not CloseReader, it would shorten expire time of torrent
+	defer t.cache.CloseReader(reader)
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	return fn(&ctxReader{reader, ctx, file.Length()})
+}
+
+// ctxReader stops waiting for pieces of torrent when context is done, it
+// stops at the end of file, torrent reader does not
+type ctxReader struct {
+	*torrstor.Reader
+	ctx  context.Context
+	size int64
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	left := r.size - r.Offset()
+	if left <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > left {
+		p = p[:left]
+	}
+	return r.Reader.ReadContext(r.ctx, p)
+}
+
+// recoverScan logs panic of parser on broken file instead of crash of server
+func recoverScan(what, hash string) {
+	if r := recover(); r != nil {
+		probeLog.Error("panic in "+what, "hash", hash, "error", r)
+	}
+}
@@ -0,0 +1,100 @@
+package torr
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+
+	"server/log"
+	sets "server/settings"
+	"server/torr/utils"
+)
+
+func newSeedStorage(path string) storage.ClientImplCloser {
+	// file storage put content to baseDir/info.Name
+	return storage.NewFileWithCompletion(filepath.Dir(path), storage.NewMapPieceCompletion())
+}
+
+// CreateTorrent hashes local file or dir, writes .torrent and starts seeding it
+func CreateTorrent(path string, pieceLength int64, trackers []string, comment, title string) (*Torrent, *metainfo.MetaInfo, error) {
+	if sets.ReadOnly {
+		return nil, nil, errors.New("db in read-only mode")
+	}
+	mi, info, err := CreateTorrentFile(path, pieceLength, trackers, comment, title)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tor, err := NewTorrent(torrent.TorrentSpecFromMetaInfo(mi), bts)
+	if err != nil {
+		return nil, mi, err
+	}
+	if !tor.GotInfo() {
+		return nil, mi, errors.New("error load created torrent")
+	}
+	tor.Title = title
+	if tor.Title == "" {
+		tor.Title = info.Name
+	}
+	SaveTorrentToDB(tor)
+	return tor, mi, nil
+}
+
+// CreateTorrentFile makes .torrent near content and saves it to db for
+// seeding by server
+func CreateTorrentFile(path string, pieceLength int64, trackers []string, comment, title string) (*metainfo.MetaInfo, *metainfo.Info, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.TLogln("Create torrent from", path)
+	mi, info, err := utils.CreateMetaInfo(path, pieceLength, trackers, comment)
+	if err != nil {
+		return nil, nil, err
+	}
+	torrentPath := path + ".torrent"
+	err = utils.WriteMetaInfo(mi, torrentPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.TLogln("Torrent file saved to", torrentPath)
+
+	if title == "" {
+		title = info.Name
+	}
+	sets.AddTorrent(&sets.TorrentDB{
+		TorrentSpec: torrent.TorrentSpecFromMetaInfo(mi),
+		Title:       title,
+		Timestamp:   time.Now().Unix(),
+		Size:        info.TotalLength(),
+		SeedPath:    path,
+	})
+	return mi, info, nil
+}
+
+func loadSeeds() {
+	for _, db := range sets.ListTorrent() {
+		if db.SeedPath == "" {
+			continue
+		}
+		log.TLogln("Start seeding", db.SeedPath)
+		go func(tor *Torrent) {
+			tr, err := NewTorrent(tor.TorrentSpec, bts)
+			if err != nil {
+				log.TLogln("Error start seeding:", err)
+				return
+			}
+			tr.Title = tor.Title
+			tr.Poster = tor.Poster
+			tr.Data = tor.Data
+			tr.Category = tor.Category
+			tr.Size = tor.Size
+			tr.Timestamp = tor.Timestamp
+			tr.GotInfo()
+		}(fromTorrentDB(db))
+	}
+}
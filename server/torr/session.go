@@ -0,0 +1,204 @@
+package torr
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sets "server/settings"
+	"server/torr/state"
+)
+
+// session counts data served by stream, it can be killed or throttled
+type session struct {
+	bytes  int64
+	offset int64
+	limit  int64
+
+	stat     state.SessionStat
+	duration float64
+	rs       io.ReadSeeker
+	killed   chan struct{}
+	kill     sync.Once
+	// last save of resume point to viewed
+	saved time.Time
+	// tail is set by first read if it's near the end of file, players read
+	// index there
+	started bool
+	tail    bool
+
+	mu        sync.Mutex
+	speed     float64
+	tickTime  time.Time
+	tickBytes int64
+	// throttle counts bytes from its start
+	limitTime  time.Time
+	limitBytes int64
+}
+
+// viewedBytes served by session marks file as viewed, small reads of players
+// probing file don't, then resume point is saved every viewedInterval,
+// streams started in last tailPercent of file aren't counted
+const (
+	viewedBytes    = 8 << 20
+	viewedInterval = 30 * time.Second
+	tailPercent    = 5
+)
+
+var (
+	sessions   = make(map[int64]*session)
+	muSessions sync.Mutex
+	lastID     int64
+
+	errSessionKilled = errors.New("session killed")
+)
+
+// newSession registers stream, duration of file in seconds is used for
+// resume position, 0 if unknown
+func newSession(stat state.SessionStat, duration float64, rs io.ReadSeeker) *session {
+	s := &session{stat: stat, duration: duration, rs: rs, killed: make(chan struct{})}
+	s.stat.Started = time.Now().Unix()
+	s.tickTime = time.Now()
+	muSessions.Lock()
+	lastID++
+	s.stat.Id = lastID
+	sessions[s.stat.Id] = s
+	muSessions.Unlock()
+	return s
+}
+
+func (s *session) close() {
+	muSessions.Lock()
+	delete(sessions, s.stat.Id)
+	muSessions.Unlock()
+	if !s.saved.IsZero() {
+		s.saveViewed()
+	}
+}
+
+// saveViewed saves offset of stream as resume point of file
+func (s *session) saveViewed() {
+	s.saved = time.Now()
+	v := &sets.Viewed{User: s.stat.User, Hash: s.stat.Hash, FileIndex: s.stat.FileId, Offset: atomic.LoadInt64(&s.offset)}
+	if s.stat.Size > 0 {
+		v.Percent = float64(v.Offset) * 100 / float64(s.stat.Size)
+		v.Position = s.duration * float64(v.Offset) / float64(s.stat.Size)
+	}
+	sets.SetViewed(v)
+}
+
+func (s *session) Read(p []byte) (int, error) {
+	select {
+	case <-s.killed:
+		return 0, errSessionKilled
+	default:
+	}
+	if !s.started {
+		s.started = true
+		s.tail = s.stat.Size > 0 && atomic.LoadInt64(&s.offset)*100 >= s.stat.Size*(100-tailPercent)
+	}
+	n, err := s.rs.Read(p)
+	offset := atomic.AddInt64(&s.offset, int64(n))
+	bytes := atomic.AddInt64(&s.bytes, int64(n))
+	// small file is viewed when it's read to the end
+	viewed := bytes >= viewedBytes || s.stat.Size < viewedBytes && offset >= s.stat.Size
+	if viewed && !s.tail && time.Since(s.saved) >= viewedInterval {
+		s.saveViewed()
+	}
+	if wait := s.account(n); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-s.killed:
+			return n, errSessionKilled
+		}
+	}
+	return n, err
+}
+
+// account updates speed and returns time to wait for throttle
+func (s *session) account(n int) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if dt := now.Sub(s.tickTime); dt >= time.Second {
+		bytes := atomic.LoadInt64(&s.bytes)
+		s.speed = float64(bytes-s.tickBytes) / dt.Seconds()
+		s.tickTime, s.tickBytes = now, bytes
+	}
+	limit := atomic.LoadInt64(&s.limit)
+	if limit <= 0 {
+		return 0
+	}
+	s.limitBytes += int64(n)
+	due := s.limitTime.Add(time.Duration(float64(s.limitBytes) / float64(limit) * float64(time.Second)))
+	return due.Sub(now)
+}
+
+func (s *session) Seek(offset int64, whence int) (int64, error) {
+	n, err := s.rs.Seek(offset, whence)
+	if err == nil {
+		atomic.StoreInt64(&s.offset, n)
+	}
+	return n, err
+}
+
+func (s *session) status() *state.SessionStat {
+	st := s.stat
+	st.Bytes = atomic.LoadInt64(&s.bytes)
+	st.Offset = atomic.LoadInt64(&s.offset)
+	st.Limit = atomic.LoadInt64(&s.limit)
+	s.mu.Lock()
+	st.Speed = s.speed
+	if dt := time.Since(s.tickTime); dt > 2*time.Second {
+		// player reads slowly or doesn't read
+		st.Speed = float64(st.Bytes-s.tickBytes) / dt.Seconds()
+	}
+	s.mu.Unlock()
+	return &st
+}
+
+// ListSessions returns active streams ordered by start
+func ListSessions() []*state.SessionStat {
+	muSessions.Lock()
+	defer muSessions.Unlock()
+	ret := make([]*state.SessionStat, 0, len(sessions))
+	for _, s := range sessions {
+		ret = append(ret, s.status())
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Id < ret[j].Id
+	})
+	return ret
+}
+
+// KillSession stops stream, false if session is not found
+func KillSession(id int64) bool {
+	muSessions.Lock()
+	s := sessions[id]
+	muSessions.Unlock()
+	if s == nil {
+		return false
+	}
+	s.kill.Do(func() {
+		close(s.killed)
+	})
+	return true
+}
+
+// ThrottleSession limits speed of stream in bytes/s, zero removes limit
+func ThrottleSession(id, limit int64) bool {
+	muSessions.Lock()
+	s := sessions[id]
+	muSessions.Unlock()
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	s.limitTime, s.limitBytes = time.Now(), 0
+	s.mu.Unlock()
+	atomic.StoreInt64(&s.limit, limit)
+	return true
+}
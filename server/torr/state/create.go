@@ -0,0 +1,14 @@
+package state
+
+// CreateJobStatus is torrent created from local content in background
+type CreateJobStatus struct {
+	Id          int    `json:"id"`
+	Path        string `json:"path"`
+	Title       string `json:"title,omitempty"`
+	State       string `json:"state"` // hashing, done, failed
+	Error       string `json:"error,omitempty"`
+	Hash        string `json:"hash,omitempty"`
+	Magnet      string `json:"magnet,omitempty"`
+	TorrentPath string `json:"torrent_path,omitempty"`
+	Added       int64  `json:"added"`
+}
@@ -0,0 +1,33 @@
+package state
+
+type MetaState int
+
+func (m MetaState) String() string {
+	switch m {
+	case MetaPending:
+		return "Metadata pending"
+	case MetaResolving:
+		return "Metadata resolving"
+	case MetaFailed:
+		return "Metadata failed"
+	default:
+		return "Metadata unknown status"
+	}
+}
+
+const (
+	MetaPending = MetaState(iota)
+	MetaResolving
+	MetaFailed
+)
+
+type MetaJobStatus struct {
+	Hash        string    `json:"hash"`
+	Title       string    `json:"title,omitempty"`
+	State       MetaState `json:"state"`
+	StateString string    `json:"state_string"`
+	Attempts    int       `json:"attempts"`
+	Error       string    `json:"error,omitempty"`
+	Added       int64     `json:"added"`
+	NextTry     int64     `json:"next_try,omitempty"`
+}
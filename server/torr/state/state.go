@@ -1,5 +1,7 @@
 package state
 
+import "server/probe"
+
 type TorrentStat int
 
 func (t TorrentStat) String() string {
@@ -34,6 +36,7 @@ type TorrentStatus struct {
 	Title               string      `json:"title"`
 	Poster              string      `json:"poster"`
 	Data                string      `json:"data,omitempty"`
+	Category            string      `json:"category,omitempty"`
 	Timestamp           int64       `json:"timestamp"`
 	Name                string      `json:"name,omitempty"`
 	Hash                string      `json:"hash,omitempty"`
@@ -63,10 +66,64 @@ type TorrentStatus struct {
 	PiecesDirtiedBad    int64       `json:"pieces_dirtied_bad,omitempty"`
 
 	FileStats []*TorrentFileStat `json:"file_stats,omitempty"`
+	Sources   []*SourceStat      `json:"sources,omitempty"`
+	Buffers   []*BufferStat      `json:"buffers,omitempty"`
 }
 
 type TorrentFileStat struct {
-	Id     int    `json:"id,omitempty"`
-	Path   string `json:"path,omitempty"`
-	Length int64  `json:"length,omitempty"`
+	Id        int             `json:"id,omitempty"`
+	Path      string          `json:"path,omitempty"`
+	Length    int64           `json:"length,omitempty"`
+	Subtitles []*SubtitleStat `json:"subtitles,omitempty"`
+	Media     *probe.Info     `json:"media,omitempty"`
+}
+
+// SubtitleStat is external subtitles file of video, served as WebVTT
+// by /subtitles/:hash/:id.vtt
+type SubtitleStat struct {
+	Id    int    `json:"id"`
+	Path  string `json:"path"`
+	Label string `json:"label,omitempty"`
+}
+
+const SourcePeers = "peers"
+
+// SourceStat is useful data got from peers or a web seed url
+type SourceStat struct {
+	Source        string  `json:"source"`
+	BytesRead     int64   `json:"bytes_read"`
+	DownloadSpeed float64 `json:"download_speed,omitempty"`
+}
+
+// BufferStat is buffer health of file reader, rates are in bytes/s
+type BufferStat struct {
+	Id              int     `json:"id"`
+	Path            string  `json:"path"`
+	Offset          int64   `json:"offset"`
+	BufferedBytes   int64   `json:"buffered_bytes"`
+	BufferedSeconds float64 `json:"buffered_seconds"`
+	Bitrate         float64 `json:"bitrate"`
+	BitrateSource   string  `json:"bitrate_source"`
+	DownloadSpeed   float64 `json:"download_speed"`
+	// seconds to buffer underrun, -1 if download is fast enough
+	StallIn float64 `json:"stall_in"`
+	Ready   bool    `json:"ready"`
+}
+
+// SessionStat is active stream of torrent file, speed and limit are in bytes/s
+type SessionStat struct {
+	Id        int64   `json:"id"`
+	Hash      string  `json:"hash"`
+	Title     string  `json:"title"`
+	FileId    int     `json:"file_id"`
+	Path      string  `json:"path"`
+	Client    string  `json:"client"`
+	User      string  `json:"user,omitempty"`
+	UserAgent string  `json:"user_agent,omitempty"`
+	Started   int64   `json:"started"`
+	Bytes     int64   `json:"bytes"`
+	Offset    int64   `json:"offset"`
+	Size      int64   `json:"size"`
+	Speed     float64 `json:"speed"`
+	Limit     int64   `json:"limit,omitempty"`
 }
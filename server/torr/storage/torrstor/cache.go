@@ -5,11 +5,13 @@ import (
 	"path/filepath"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/anacrolix/torrent"
 
 	"server/log"
+	"server/metrics"
 	"server/settings"
 	"server/torr/storage/state"
 	"server/torr/utils"
@@ -19,6 +21,8 @@ import (
 )
 
 type Cache struct {
+	evictions int64 // first for 64-bit atomic alignment on 32-bit platforms
+
 	storage.TorrentImpl
 	storage *Storage
 
@@ -52,6 +56,19 @@ func NewCache(capacity int64, storage *Storage) *Cache {
 	return ret
 }
 
+// NewReadCache creates cache without pieces for torrents with own storage,
+// it only tracks readers
+func NewReadCache(info *metainfo.Info, hash metainfo.Hash) *Cache {
+	ret := NewCache(settings.BTsets.CacheSize, nil)
+	if ret.capacity == 0 {
+		ret.capacity = info.PieceLength * 4
+	}
+	ret.pieceLength = info.PieceLength
+	ret.pieceCount = info.NumPieces()
+	ret.hash = hash
+	return ret
+}
+
 func (c *Cache) Init(info *metainfo.Info, hash metainfo.Hash) {
 	log.TLogln("Create cache for:", info.Name, hash.HexString())
 	if c.capacity == 0 {
@@ -117,9 +134,16 @@ func (c *Cache) Close() error {
 func (c *Cache) removePiece(piece *Piece) {
 	if !c.isClosed {
 		piece.Release()
+		atomic.AddInt64(&c.evictions, 1)
+		metrics.CacheEvictions.Inc()
 	}
 }
 
+// Evictions returns count of pieces removed to free space
+func (c *Cache) Evictions() int64 {
+	return atomic.LoadInt64(&c.evictions)
+}
+
 func (c *Cache) AdjustRA(readahead int64) {
 	if settings.BTsets.CacheSize == 0 {
 		c.capacity = readahead * 3
@@ -133,6 +157,22 @@ func (c *Cache) AdjustRA(readahead int64) {
 	}
 }
 
+// GetReaders returns opened readers
+func (c *Cache) GetReaders() []*Reader {
+	if c == nil {
+		return nil
+	}
+	c.muReaders.Lock()
+	defer c.muReaders.Unlock()
+	list := make([]*Reader, 0, len(c.readers))
+	for r := range c.readers {
+		if !r.isClosed {
+			list = append(list, r)
+		}
+	}
+	return list
+}
+
 func (c *Cache) GetState() *state.CacheState {
 	cState := new(state.CacheState)
 
@@ -261,7 +301,7 @@ func (c *Cache) getRemPieces() []*Piece {
 		}
 		limit := 0
 		for i := readerPos; i < end && limit < count; i++ {
-			if !c.pieces[i].Complete {
+			if p, ok := c.pieces[i]; ok && !p.Complete {
 				if i == readerPos {
 					c.torrent.Piece(i).SetPriority(torrent.PiecePriorityNow)
 				} else if i == readerPos+1 {
@@ -1,6 +1,7 @@
 package torrstor
 
 import (
+	"context"
 	"io"
 	"strings"
 	"sync"
@@ -12,6 +13,8 @@ import (
 	"server/settings"
 )
 
+const minRateTime = 30 * time.Second
+
 type Reader struct {
 	torrent.Reader
 	offset    int64
@@ -26,6 +29,10 @@ type Reader struct {
 	isUse      bool
 	mu         sync.Mutex
 	ranges     Range
+
+	// playback rate
+	started   time.Time
+	readBytes int64
 }
 
 func newReader(file *torrent.File, cache *Cache) *Reader {
@@ -63,13 +70,21 @@ func (r *Reader) Seek(offset int64, whence int) (n int64, err error) {
 }
 
 func (r *Reader) Read(p []byte) (n int, err error) {
+	return r.ReadContext(context.Background(), p)
+}
+
+// ReadContext reads like Read but stops waiting for pieces when ctx is done
+func (r *Reader) ReadContext(ctx context.Context, p []byte) (n int, err error) {
 	err = io.EOF
 	if r.isClosed {
 		return
 	}
 	if r.file.Torrent() != nil && r.file.Torrent().Info() != nil {
 		r.readerOn()
-		n, err = r.Reader.Read(p)
+		if r.started.IsZero() {
+			r.started = time.Now()
+		}
+		n, err = r.Reader.ReadContext(ctx, p)
 
 		//samsung tv fix xvid/divx
 		if r.offset == 0 && len(p) >= 192 {
@@ -90,6 +105,7 @@ func (r *Reader) Read(p []byte) (n int, err error) {
 		}
 
 		r.offset += int64(n)
+		r.readBytes += int64(n)
 		r.lastAccess = time.Now().Unix()
 	} else {
 		log.TLogln("Torrent closed and readed")
@@ -115,6 +131,23 @@ func (r *Reader) Readahead() int64 {
 	return r.readahead
 }
 
+func (r *Reader) File() *torrent.File {
+	return r.file
+}
+
+// ReadRate returns average read speed of player in bytes/s,
+// zero until the reader is used for minRateTime
+func (r *Reader) ReadRate() float64 {
+	if r.started.IsZero() {
+		return 0
+	}
+	elapsed := time.Since(r.started)
+	if elapsed < minRateTime {
+		return 0
+	}
+	return float64(r.readBytes) / elapsed.Seconds()
+}
+
 func (r *Reader) Close() {
 	// file reader close in gotorrent
 	// this struct close in cache
@@ -142,6 +175,21 @@ func (r *Reader) getPieceNum(offset int64) int {
 	return int((offset + r.file.Offset()) / r.cache.pieceLength)
 }
 
+// AheadSize returns size of cache window after reader offset, pieces in it
+// are downloaded with priority
+func (r *Reader) AheadSize() int64 {
+	if r.cache == nil {
+		return 0
+	}
+	r.cache.muReaders.Lock()
+	readers := int64(r.getUseReaders())
+	r.cache.muReaders.Unlock()
+	if readers == 0 {
+		readers = 1
+	}
+	return (r.cache.capacity / readers) * int64(settings.BTsets.ReaderReadAHead) / 100
+}
+
 func (r *Reader) getOffsetRange() (int64, int64) {
 	prc := int64(settings.BTsets.ReaderReadAHead)
 	readers := int64(r.getUseReaders())
@@ -4,21 +4,24 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/anacrolix/dms/dlna"
 	"github.com/anacrolix/missinggo/httptoo"
-	"github.com/anacrolix/torrent"
 
+	"server/log"
+	"server/metrics"
 	mt "server/mimetype"
-	sets "server/settings"
 	"server/torr/state"
 )
 
-func (t *Torrent) Stream(fileID int, req *http.Request, resp http.ResponseWriter) error {
+var streamLog = log.Component("stream")
+
+// Stream serves file of torrent, user is authorized user of request or empty
+func (t *Torrent) Stream(fileID int, user string, req *http.Request, resp http.ResponseWriter) error {
 	if !t.GotInfo() {
 		http.NotFound(resp, req)
 		return errors.New("torrent don't get info")
@@ -36,37 +39,39 @@ func (t *Torrent) Stream(fileID int, req *http.Request, resp http.ResponseWriter
 		return fmt.Errorf("file with id %v not found", fileID)
 	}
 
-	files := t.Files()
-	var file *torrent.File
-	for _, tfile := range files {
-		if tfile.Path() == stFile.Path {
-			file = tfile
-			break
-		}
-	}
-	if file == nil {
-		return fmt.Errorf("file with id %v not found", fileID)
+	fr, err := t.openFile(fileID)
+	if err != nil {
+		return err
 	}
 
-	reader := t.NewReader(file)
-
-	host, port, err := net.SplitHostPort(req.RemoteAddr)
-	if sets.BTsets.EnableDebug {
-		if err != nil {
-			log.Println("Connect client")
-		} else {
-			log.Println("Connect client", host, port)
-		}
+	client := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		client = host
 	}
-
-	sets.SetViewed(&sets.Viewed{t.Hash().HexString(), fileID})
+	duration := 0.0
+	if stFile.Media != nil {
+		duration = stFile.Media.Duration
+	}
+	sess := newSession(state.SessionStat{
+		Hash:      t.Hash().HexString(),
+		Title:     t.Title,
+		FileId:    fileID,
+		Path:      fr.path,
+		Client:    client,
+		User:      user,
+		UserAgent: req.UserAgent(),
+		Size:      fr.size,
+	}, duration, fr)
+	defer sess.close()
+	clientLog := streamLog.With("hash", t.Hash().HexString(), "client", client, "session", sess.stat.Id)
+	clientLog.Debug("connect client", "file", fr.path, "range", req.Header.Get("Range"))
 
 	resp.Header().Set("Connection", "close")
-	etag := hex.EncodeToString([]byte(fmt.Sprintf("%s/%s", t.Hash().HexString(), file.Path())))
+	etag := hex.EncodeToString([]byte(fmt.Sprintf("%s/%s", t.Hash().HexString(), fr.path)))
 	resp.Header().Set("ETag", httptoo.EncodeQuotedString(etag))
 	// DLNA headers
 	resp.Header().Set("transferMode.dlna.org", "Streaming")
-	mime, err := mt.MimeTypeByPath(file.Path())
+	mime, err := mt.MimeTypeByPath(fr.path)
 	if err == nil && mime.IsMedia() {
 		resp.Header().Set("content-type", mime.String())
 	}
@@ -77,14 +82,16 @@ func (t *Torrent) Stream(fileID int, req *http.Request, resp http.ResponseWriter
 		}.String())
 	}
 
-	http.ServeContent(resp, req, file.Path(), time.Unix(t.Timestamp, 0), reader)
+	metrics.StreamsTotal.Inc()
+	metrics.Streams.Add(1)
+	http.ServeContent(resp, req, fr.path, time.Unix(t.Timestamp, 0), sess)
+	metrics.Streams.Add(-1)
 
-	t.CloseReader(reader)
-	if sets.BTsets.EnableDebug {
-		if err != nil {
-			log.Println("Disconnect client")
-		} else {
-			log.Println("Disconnect client", host, port)
+	fr.close()
+	clientLog.Debug("disconnect client", "file", fr.path, "bytes", atomic.LoadInt64(&sess.bytes))
+	if stFile.Media == nil {
+		if file := t.findFileIndex(fileID); file != nil && file.Path() == fr.path {
+			go t.probeStreamed(file)
 		}
 	}
 	return nil
@@ -0,0 +1,62 @@
+package torr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"server/subtitles"
+	"server/torr/state"
+	utils2 "server/utils"
+)
+
+// maxReadFile limits file read into memory
+const maxReadFile = 20 << 20
+
+// attachSubtitles lists subtitles files with name of video, or all of them
+// if torrent has one video
+func attachSubtitles(files []*state.TorrentFileStat) {
+	var videos, subs []*state.TorrentFileStat
+	for _, f := range files {
+		if subtitles.IsSubtitle(f.Path) {
+			subs = append(subs, f)
+		} else if utils2.GetMimeType(f.Path) == "video/*" {
+			videos = append(videos, f)
+		}
+	}
+	if len(subs) == 0 {
+		return
+	}
+	for _, v := range videos {
+		name := filepath.Base(strings.TrimSuffix(v.Path, filepath.Ext(v.Path)))
+		for _, s := range subs {
+			if len(videos) == 1 || strings.Contains(s.Path, name) {
+				v.Subtitles = append(v.Subtitles, &state.SubtitleStat{
+					Id:    s.Id,
+					Path:  s.Path,
+					Label: subtitles.Label(v.Path, s.Path),
+				})
+			}
+		}
+	}
+}
+
+// ReadFile returns path and content of small file, e.g. subtitles
+func (t *Torrent) ReadFile(id int) (string, []byte, error) {
+	if !t.GotInfo() {
+		return "", nil, errors.New("torrent don't get info")
+	}
+	fr, err := t.openFile(id)
+	if err != nil {
+		return "", nil, err
+	}
+	defer fr.close()
+	if fr.size > maxReadFile {
+		return "", nil, fmt.Errorf("file is too big: %v", fr.size)
+	}
+	// reader does not stop at the end of file
+	buf, err := io.ReadAll(io.LimitReader(fr, fr.size))
+	return fr.path, buf, err
+}
@@ -8,8 +8,13 @@ import (
 
 	"github.com/anacrolix/torrent"
 	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
 
+	"server/archive"
+	"server/disc"
 	"server/log"
+	"server/metrics"
+	"server/probe"
 	"server/settings"
 	"server/torr/state"
 	cacheSt "server/torr/storage/state"
@@ -17,21 +22,46 @@ import (
 	"server/torr/utils"
 )
 
+var torrLog = log.Component("torrent")
+
 type Torrent struct {
-	Title  string
-	Poster string
-	Data   string
+	Title    string
+	Poster   string
+	Data     string
+	Category string
 	*torrent.TorrentSpec
+	// spec before trackers of settings were added to it
+	addSpec *torrent.TorrentSpec
 
 	Stat      state.TorrentStat
 	Timestamp int64
 	Size      int64
+	SeedPath  string
+	// file indexes in torrent order to show, all if empty
+	SelectOnly []int
 
 	*torrent.Torrent
 	muTorrent sync.Mutex
 
-	bt    *BTServer
-	cache *torrstor.Cache
+	bt       *BTServer
+	cache    *torrstor.Cache
+	seedStor storage.ClientImplCloser
+	webSeeds []*webSeed
+	// media durations by file path
+	durations map[string]float64
+	// media info by file path
+	media map[string]*probe.Info
+	// entries of zip files by file path
+	archives map[string][]*archive.Entry
+	// main titles of DVD and Blu-ray discs by disc root
+	titles map[string][]*disc.Title
+	// split files played as one
+	joined []*joinedFile
+	// ids of virtual files by path, saved in db
+	virtualIDs map[string]int
+	// paths of probed files
+	probed    map[string]bool
+	probeOnce sync.Once
 
 	lastTimeSpeed       time.Time
 	DownloadSpeed       float64
@@ -44,6 +74,9 @@ type Torrent struct {
 
 	expiredTime time.Time
 
+	added       time.Time
+	resolveOnce sync.Once
+
 	closed <-chan struct{}
 
 	progressTicker *time.Ticker
@@ -54,6 +87,8 @@ func NewTorrent(spec *torrent.TorrentSpec, bt *BTServer) (*Torrent, error) {
 	if bt == nil || bt.client == nil {
 		return nil, errors.New("BT client not connected")
 	}
+	// metadata retries add torrent again with spec as it was added
+	addSpec := cloneSpec(spec)
 	switch settings.BTsets.RetrackersMode {
 	case 1:
 		spec.Trackers = append(spec.Trackers, [][]string{utils.GetDefTrackers()}...)
@@ -68,7 +103,31 @@ func NewTorrent(spec *torrent.TorrentSpec, bt *BTServer) (*Torrent, error) {
 		spec.Trackers = append(spec.Trackers, [][]string{trackers}...)
 	}
 
-	goTorrent, _, err := bt.client.AddTorrentSpec(spec)
+	// local content saved for seeding
+	seedPath := ""
+	if spec.Storage == nil {
+		if db := settings.GetTorrent(spec.InfoHash); db != nil {
+			seedPath = db.SeedPath
+		}
+	}
+	var seedStor storage.ClientImplCloser
+	if seedPath != "" {
+		seedStor = newSeedStorage(seedPath)
+		spec.Storage = seedStor
+	}
+
+	// web seeds added after to track them
+	webSeeds := spec.Webseeds
+	spec.Webseeds = nil
+	goTorrent, isNew, err := bt.client.AddTorrentSpec(spec)
+	spec.Webseeds = webSeeds
+	if seedStor != nil {
+		spec.Storage = nil
+		if !isNew || err != nil {
+			seedStor.Close()
+			seedStor = nil
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +135,7 @@ func NewTorrent(spec *torrent.TorrentSpec, bt *BTServer) (*Torrent, error) {
 	bt.mu.Lock()
 	defer bt.mu.Unlock()
 	if tor, ok := bt.torrents[spec.InfoHash]; ok {
+		tor.AddWebSeeds(webSeeds)
 		return tor, nil
 	}
 
@@ -86,8 +146,13 @@ func NewTorrent(spec *torrent.TorrentSpec, bt *BTServer) (*Torrent, error) {
 	torr.bt = bt
 	torr.closed = goTorrent.Closed()
 	torr.TorrentSpec = spec
+	torr.addSpec = addSpec
+	torr.SeedPath = seedPath
+	torr.seedStor = seedStor
 	torr.AddExpiredTime(time.Minute)
 	torr.Timestamp = time.Now().Unix()
+	torr.added = time.Now()
+	torr.AddWebSeeds(webSeeds)
 
 	go torr.watch()
 
@@ -96,20 +161,36 @@ func NewTorrent(spec *torrent.TorrentSpec, bt *BTServer) (*Torrent, error) {
 }
 
 func (t *Torrent) WaitInfo() bool {
+	return t.waitInfo(metaTimeout(), nil)
+}
+
+func (t *Torrent) waitInfo(timeout time.Duration, cancel <-chan struct{}) bool {
 	if t.Torrent == nil {
 		return false
 	}
 
-	// Close torrent if not info while 5 minutes
-	tm := time.NewTimer(time.Minute * 5)
+	// Close torrent if not info while timeout
+	tm := time.NewTimer(timeout)
+	defer tm.Stop()
 
 	select {
 	case <-t.Torrent.GotInfo():
-		t.cache = t.bt.storage.GetCache(t.Hash())
+		if t.cache == nil {
+			t.cache = t.bt.storage.GetCache(t.Hash())
+			if t.cache == nil {
+				// torrent with own storage, e.g. seeding local content
+				t.cache = torrstor.NewReadCache(t.Info(), t.Hash())
+			}
+		}
 		t.cache.SetTorrent(t.Torrent)
+		t.resolveOnce.Do(func() {
+			metrics.ResolveDuration.Observe(time.Since(t.added).Seconds())
+		})
 		return true
 	case <-t.closed:
 		return false
+	case <-cancel:
+		return false
 	case <-tm.C:
 		return false
 	}
@@ -123,6 +204,10 @@ func (t *Torrent) GotInfo() bool {
 	if t.WaitInfo() {
 		t.Stat = state.TorrentWorking
 		t.AddExpiredTime(time.Minute * 5)
+		t.probeOnce.Do(func() {
+			t.joinFiles(t.Files())
+			go t.probeFiles()
+		})
 		return true
 	} else {
 		t.Close()
@@ -151,7 +236,7 @@ func (t *Torrent) watch() {
 func (t *Torrent) progressEvent() {
 	if t.expired() {
 		if t.TorrentSpec != nil {
-			log.TLogln("Torrent close by timeout", t.TorrentSpec.InfoHash.HexString())
+			torrLog.Info("torrent close by timeout", "hash", t.TorrentSpec.InfoHash.HexString())
 		}
 		t.bt.RemoveTorrent(t.Hash())
 		return
@@ -200,6 +285,9 @@ func (t *Torrent) updateRA() {
 }
 
 func (t *Torrent) expired() bool {
+	if t.SeedPath != "" {
+		return false
+	}
 	return t.cache.Readers() == 0 && t.expiredTime.Before(time.Now()) && (t.Stat == state.TorrentWorking || t.Stat == state.TorrentClosed)
 }
 
@@ -211,6 +299,16 @@ func (t *Torrent) Files() []*torrent.File {
 	return nil
 }
 
+// sortedFiles returns copy of files sorted by path, ids of files are given
+// in this order, files of torrent keep their order for so= indexes
+func (t *Torrent) sortedFiles() []*torrent.File {
+	files := append([]*torrent.File(nil), t.Files()...)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path() < files[j].Path()
+	})
+	return files
+}
+
 func (t *Torrent) Hash() metainfo.Hash {
 	if t.Torrent != nil {
 		t.Torrent.InfoHash()
@@ -262,6 +360,10 @@ func (t *Torrent) Close() {
 	t.bt.mu.Unlock()
 
 	t.drop()
+	if t.seedStor != nil {
+		t.seedStor.Close()
+		t.seedStor = nil
+	}
 }
 
 func (t *Torrent) Status() *state.TorrentStatus {
@@ -275,6 +377,7 @@ func (t *Torrent) Status() *state.TorrentStatus {
 	st.Title = t.Title
 	st.Poster = t.Poster
 	st.Data = t.Data
+	st.Category = t.Category
 	st.Timestamp = t.Timestamp
 	st.TorrentSize = t.Size
 
@@ -308,27 +411,60 @@ func (t *Torrent) Status() *state.TorrentStatus {
 		st.ActivePeers = tst.ActivePeers
 		st.ConnectedSeeders = tst.ConnectedSeeders
 		st.HalfOpenPeers = tst.HalfOpenPeers
+		st.Sources = t.sourceStats(st.BytesReadUsefulData)
 
 		if t.Torrent.Info() != nil {
 			st.TorrentSize = t.Torrent.Length()
 
-			files := t.Files()
-			sort.Slice(files, func(i, j int) bool {
-				return files[i].Path() < files[j].Path()
-			})
+			selected := t.selectedFiles(t.Files())
+			files := t.sortedFiles()
 			for i, f := range files {
+				if selected != nil && !selected[f] {
+					continue
+				}
 				st.FileStats = append(st.FileStats, &state.TorrentFileStat{
 					Id:     i + 1, // in web id 0 is undefined
 					Path:   f.Path(),
 					Length: f.Length(),
+					Media:  t.media[f.Path()],
 				})
 			}
+			for _, vf := range t.virtualFiles(files) {
+				if selected != nil && !selected[vf.files[0]] {
+					continue
+				}
+				st.FileStats = append(st.FileStats, &state.TorrentFileStat{
+					Id:     vf.id,
+					Path:   vf.path,
+					Length: vf.size,
+					Media:  t.media[vf.path],
+				})
+			}
+			attachSubtitles(st.FileStats)
+			st.Buffers = t.bufferStats()
 		}
 	}
 
 	return st
 }
 
+// selectedFiles returns files of so= indexes, files must be in torrent order
+func (t *Torrent) selectedFiles(files []*torrent.File) map[*torrent.File]bool {
+	if len(t.SelectOnly) == 0 {
+		return nil
+	}
+	selected := make(map[*torrent.File]bool)
+	for _, i := range t.SelectOnly {
+		if i >= 0 && i < len(files) {
+			selected[files[i]] = true
+		}
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+	return selected
+}
+
 func (t *Torrent) CacheState() *cacheSt.CacheState {
 	if t.Torrent != nil && t.cache != nil {
 		st := t.cache.GetState()
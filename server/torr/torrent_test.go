@@ -0,0 +1,110 @@
+package torr
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+
+	"server/settings"
+	"server/torr/storage/torrstor"
+)
+
+// testTorrent adds torrent with files of info to client without network
+func testTorrent(t *testing.T, info *metainfo.Info, callbacks ...torrent.Callbacks) *torrent.Torrent {
+	cfg := torrent.NewDefaultClientConfig()
+	for _, cb := range callbacks {
+		cfg.Callbacks = cb
+	}
+	cfg.DataDir = t.TempDir()
+	cfg.ListenPort = 0
+	cfg.NoDHT = true
+	cfg.DisableTCP = true
+	cfg.DisableUTP = true
+	cfg.DisableTrackers = true
+	cfg.NoDefaultPortForwarding = true
+	cl, err := torrent.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cl.Close() })
+	tt, err := cl.AddTorrent(&metainfo.MetaInfo{InfoBytes: bencode.MustMarshal(info)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tt
+}
+
+func TestStatusSelectOnly(t *testing.T) {
+	if settings.BTsets == nil {
+		settings.BTsets = new(settings.BTSets)
+	}
+	// files aren't sorted by path in torrent
+	info := &metainfo.Info{
+		Name:        "pack",
+		PieceLength: 16 << 10,
+		Pieces:      make([]byte, 20),
+		Files: []metainfo.FileInfo{
+			{Path: []string{"b.mkv"}, Length: 10},
+			{Path: []string{"a.mkv"}, Length: 10},
+			{Path: []string{"c.mkv"}, Length: 10},
+		},
+	}
+	tt := testTorrent(t, info)
+	tor := &Torrent{Torrent: tt, SelectOnly: []int{0, 2}}
+	tor.cache = torrstor.NewReadCache(tt.Info(), tt.InfoHash())
+
+	for i := 0; i < 2; i++ {
+		st := tor.Status()
+		if len(st.FileStats) != 2 || st.FileStats[0].Path != "pack/b.mkv" || st.FileStats[0].Id != 2 ||
+			st.FileStats[1].Path != "pack/c.mkv" || st.FileStats[1].Id != 3 {
+			t.Fatalf("wrong selected files %+v %+v", st.FileStats[0], st.FileStats[len(st.FileStats)-1])
+		}
+	}
+	if tt.Files()[0].Path() != "pack/b.mkv" {
+		t.Fatalf("files of torrent are reordered, first is %v", tt.Files()[0].Path())
+	}
+}
+
+func TestWebSeedPeers(t *testing.T) {
+	bt := &BTServer{config: torrent.NewDefaultClientConfig()}
+	bt.setWebSeedCallbacks()
+	var tors []*Torrent
+	for i := 0; i < 2; i++ {
+		info := &metainfo.Info{
+			Name:        "file" + strconv.Itoa(i),
+			PieceLength: 16 << 10,
+			Pieces:      make([]byte, 20),
+			Length:      10,
+		}
+		tors = append(tors, &Torrent{Torrent: testTorrent(t, info, bt.config.Callbacks)})
+	}
+	// web seeds are added by torrents at same time
+	var wg sync.WaitGroup
+	for i, tor := range tors {
+		wg.Add(1)
+		go func(i int, tor *Torrent) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				tor.AddWebSeeds([]string{fmt.Sprintf("http://127.0.0.%d:1/%d", i+1, j)})
+			}
+		}(i, tor)
+	}
+	wg.Wait()
+	for i, tor := range tors {
+		tor.muTorrent.Lock()
+		for _, ws := range tor.webSeeds {
+			wsMu.Lock()
+			peer := ws.peer
+			wsMu.Unlock()
+			if peer == nil || peer.RemoteAddr.String() != fmt.Sprintf("127.0.0.%d:1", i+1) {
+				t.Errorf("web seed %v has wrong peer %v", ws.url, peer)
+			}
+		}
+		tor.muTorrent.Unlock()
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+
+	"server/version"
+)
+
+// CreateMetaInfo hashes file or dir content, pieceLength 0 - choose by size
+func CreateMetaInfo(path string, pieceLength int64, trackers []string, comment string) (*metainfo.MetaInfo, *metainfo.Info, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err = os.Stat(path); err != nil {
+		return nil, nil, err
+	}
+	if pieceLength < 0 || (pieceLength > 0 && pieceLength&(pieceLength-1) != 0) {
+		return nil, nil, errors.New("piece length must be power of two")
+	}
+
+	info := metainfo.Info{PieceLength: pieceLength}
+	err = info.BuildFromFilePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.TotalLength() == 0 {
+		return nil, nil, errors.New("content is empty")
+	}
+
+	mi := &metainfo.MetaInfo{Comment: comment}
+	mi.SetDefaults()
+	mi.CreatedBy = "TorrServer/" + version.Version
+	for _, tr := range trackers {
+		mi.AnnounceList = append(mi.AnnounceList, []string{tr})
+	}
+	if len(trackers) > 0 {
+		mi.Announce = trackers[0]
+	}
+	mi.InfoBytes, err = bencode.Marshal(info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mi, &info, nil
+}
+
+func WriteMetaInfo(mi *metainfo.MetaInfo, path string) error {
+	ff, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = mi.Write(ff)
+	if cerr := ff.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -4,10 +4,11 @@ import (
 	"encoding/base32"
 	"io/ioutil"
 	"math/rand"
-	"net/http"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"server/proxy"
 	"server/settings"
 
 	"golang.org/x/time/rate"
@@ -54,12 +55,19 @@ func GetDefTrackers() []string {
 	return loadedTrackers
 }
 
+// LoadedTrackers returns count of trackers loaded from web list, 0 if not loaded yet
+func LoadedTrackers() int {
+	return len(loadedTrackers)
+}
+
 func loadNewTracker() {
 	if len(loadedTrackers) > 0 {
 		return
 	}
-	resp, err := http.Get("https://raw.githubusercontent.com/ngosang/trackerslist/master/trackers_best_ip.txt")
+	client := proxy.HTTPClient(proxy.HTTP, time.Minute)
+	resp, err := client.Get("https://raw.githubusercontent.com/ngosang/trackerslist/master/trackers_best_ip.txt")
 	if err == nil {
+		defer resp.Body.Close()
 		buf, err := ioutil.ReadAll(resp.Body)
 		if err == nil {
 			arr := strings.Split(string(buf), "\n")
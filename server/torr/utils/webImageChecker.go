@@ -4,15 +4,17 @@ import (
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
-	"net/http"
+	"time"
+
 	"server/log"
+	"server/proxy"
 )
 
 func CheckImgUrl(link string) bool {
 	if link == "" {
 		return false
 	}
-	resp, err := http.Get(link)
+	resp, err := proxy.HTTPClient(proxy.HTTP, time.Minute).Get(link)
 	if err != nil {
 		log.TLogln("Error check image:", err)
 		return false
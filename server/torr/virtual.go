@@ -0,0 +1,172 @@
+package torr
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/anacrolix/torrent"
+
+	"server/archive"
+	"server/disc"
+	"server/settings"
+)
+
+// virtualFile is joined split file, archive entry or disc title shown as
+// file of torrent
+type virtualFile struct {
+	id   int
+	path string
+	size int64
+	// files of torrent with data, archive or parts
+	files []*torrent.File
+	entry *archive.Entry
+}
+
+// virtualFiles lists archive entries, disc titles and joined files, ids of
+// them follow torrent files and are kept in db as they are found, so they
+// don't depend on order of scans, muTorrent must be locked
+func (t *Torrent) virtualFiles(files []*torrent.File) []*virtualFile {
+	if len(t.joined) == 0 && len(t.archives) == 0 && len(t.titles) == 0 {
+		return nil
+	}
+	byPath := make(map[string]*torrent.File, len(files))
+	for _, f := range files {
+		byPath[f.Path()] = f
+	}
+	var ret []*virtualFile
+	zips := make([]string, 0, len(t.archives))
+	for p := range t.archives {
+		zips = append(zips, p)
+	}
+	sort.Strings(zips)
+	for _, p := range zips {
+		if byPath[p] == nil {
+			continue
+		}
+		for _, e := range t.archives[p] {
+			path := p + "/" + e.Name
+			ret = append(ret, &virtualFile{id: t.virtualID(path, len(files)), path: path, size: e.Size, files: []*torrent.File{byPath[p]}, entry: e})
+		}
+	}
+	roots := make([]string, 0, len(t.titles))
+	for root := range t.titles {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	for _, root := range roots {
+		for _, title := range t.titles[root] {
+			path := disc.TitlePath(root, title)
+			ret = appendParts(ret, &virtualFile{id: t.virtualID(path, len(files)), path: path}, title.Parts, byPath)
+		}
+	}
+	for _, jf := range t.joined {
+		ret = appendParts(ret, &virtualFile{id: t.virtualID(jf.Path, len(files)), path: jf.Path}, jf.Parts, byPath)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].id < ret[j].id
+	})
+	return ret
+}
+
+// virtualID returns saved id of virtual file or next free id after count
+// of torrent files, muTorrent must be locked
+func (t *Torrent) virtualID(path string, count int) int {
+	hash := t.TorrentSpec.InfoHash.HexString()
+	if t.virtualIDs == nil {
+		t.virtualIDs = settings.GetFileIDs(hash)
+	}
+	if id, ok := t.virtualIDs[path]; ok {
+		return id
+	}
+	id := count
+	for _, v := range t.virtualIDs {
+		if v > id {
+			id = v
+		}
+	}
+	id++
+	t.virtualIDs[path] = id
+	settings.SetFileIDs(hash, t.virtualIDs)
+	return id
+}
+
+// appendParts adds file played as parts if all of them are in torrent
+func appendParts(list []*virtualFile, vf *virtualFile, parts []string, byPath map[string]*torrent.File) []*virtualFile {
+	for _, p := range parts {
+		f := byPath[p]
+		if f == nil {
+			return list
+		}
+		vf.files = append(vf.files, f)
+		vf.size += f.Length()
+	}
+	return append(list, vf)
+}
+
+func (t *Torrent) virtualFile(id int) *virtualFile {
+	t.muTorrent.Lock()
+	defer t.muTorrent.Unlock()
+	if t.Torrent == nil || t.Torrent.Info() == nil {
+		return nil
+	}
+	for _, vf := range t.virtualFiles(t.Files()) {
+		if vf.id == id {
+			return vf
+		}
+	}
+	return nil
+}
+
+// fileReader reads torrent file or virtual file
+type fileReader struct {
+	io.ReadSeeker
+	path  string
+	size  int64
+	close func()
+}
+
+// openFile returns reader of file by id, it must be closed
+func (t *Torrent) openFile(id int) (*fileReader, error) {
+	files := t.Files()
+	for path, fid := range fileIDs(files) {
+		if fid != id {
+			continue
+		}
+		for _, file := range files {
+			if file.Path() == path {
+				reader := t.NewReader(file)
+				if reader == nil {
+					return nil, errClosed
+				}
+				return &fileReader{reader, path, file.Length(), func() { t.CloseReader(reader) }}, nil
+			}
+		}
+	}
+	vf := t.virtualFile(id)
+	if vf == nil {
+		return nil, fmt.Errorf("file with id %v not found", id)
+	}
+	if vf.entry == nil {
+		c := newConcatReader(t, vf.files)
+		return &fileReader{c, vf.path, vf.size, c.Close}, nil
+	}
+	reader := t.NewReader(vf.files[0])
+	if reader == nil {
+		return nil, errClosed
+	}
+	rs, err := archive.OpenEntry(archive.NewReaderAt(reader), vf.entry)
+	if err != nil {
+		t.CloseReader(reader)
+		return nil, err
+	}
+	return &fileReader{rs, vf.path, vf.size, func() { t.CloseReader(reader) }}, nil
+}
+
+// HasFile checks that torrent has file or virtual file with id
+func (t *Torrent) HasFile(id int) bool {
+	if id >= 1 && id <= len(t.Files()) {
+		return true
+	}
+	return t.virtualFile(id) != nil
+}
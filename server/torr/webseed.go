@@ -0,0 +1,196 @@
+package torr
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+
+	sets "server/settings"
+	"server/torr/state"
+)
+
+type webSeed struct {
+	url   string
+	host  string
+	bytes int64
+	peer  *torrent.Peer
+}
+
+var (
+	// torrent lib don't say which url a web seed peer belongs to, so web seeds
+	// added one by one and the peer is caught in NewPeer callback
+	wsAddMu sync.Mutex
+
+	// wsMu guards wsAdding, wsPeers and peers of web seeds
+	wsMu     sync.Mutex
+	wsAdding *webSeed
+	wsPeers  = make(map[*torrent.Peer]*webSeed)
+)
+
+func (bt *BTServer) setWebSeedCallbacks() {
+	cb := &bt.config.Callbacks
+	cb.NewPeer = append(cb.NewPeer, func(p *torrent.Peer) {
+		if p.Network != "http" {
+			return
+		}
+		wsMu.Lock()
+		defer wsMu.Unlock()
+		// web seeds of torrent spec are added by other torrents at same time
+		if wsAdding == nil || p.RemoteAddr == nil || p.RemoteAddr.String() != wsAdding.host {
+			return
+		}
+		wsAdding.peer = p
+		wsPeers[p] = wsAdding
+	})
+	cb.ReceivedUsefulData = append(cb.ReceivedUsefulData, func(ev torrent.ReceivedUsefulDataEvent) {
+		if ev.Peer.Network != "http" || ev.Message == nil {
+			return
+		}
+		wsMu.Lock()
+		ws := wsPeers[ev.Peer]
+		wsMu.Unlock()
+		if ws != nil {
+			atomic.AddInt64(&ws.bytes, int64(len(ev.Message.Piece)))
+		}
+	})
+	cb.PeerClosed = append(cb.PeerClosed, func(p *torrent.Peer) {
+		if p.Network != "http" {
+			return
+		}
+		wsMu.Lock()
+		if ws, ok := wsPeers[p]; ok {
+			ws.peer = nil
+			delete(wsPeers, p)
+		}
+		wsMu.Unlock()
+	})
+}
+
+// AddWebSeeds adds http sources to torrent, urls already added are skipped
+func (t *Torrent) AddWebSeeds(urls []string) {
+	for _, u := range urls {
+		if u == "" || t.hasWebSeed(u) {
+			continue
+		}
+		ws := &webSeed{url: u}
+		if pu, err := url.Parse(u); err == nil {
+			ws.host = pu.Host
+		}
+		t.muTorrent.Lock()
+		if t.Torrent != nil {
+			wsAddMu.Lock()
+			setWsAdding(ws)
+			t.Torrent.AddWebSeeds([]string{u})
+			setWsAdding(nil)
+			wsAddMu.Unlock()
+		}
+		t.webSeeds = append(t.webSeeds, ws)
+		if t.TorrentSpec != nil && !contains(t.TorrentSpec.Webseeds, u) {
+			t.TorrentSpec.Webseeds = append(t.TorrentSpec.Webseeds, u)
+		}
+		t.muTorrent.Unlock()
+	}
+}
+
+func setWsAdding(ws *webSeed) {
+	wsMu.Lock()
+	wsAdding = ws
+	wsMu.Unlock()
+}
+
+func (t *Torrent) hasWebSeed(u string) bool {
+	t.muTorrent.Lock()
+	defer t.muTorrent.Unlock()
+	for _, ws := range t.webSeeds {
+		if ws.url == u {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceStats returns bytes got from peers and each web seed, muTorrent must be locked
+func (t *Torrent) sourceStats(usefulBytes int64) []*state.SourceStat {
+	if len(t.webSeeds) == 0 {
+		return nil
+	}
+	peers := &state.SourceStat{Source: state.SourcePeers}
+	stats := []*state.SourceStat{peers}
+	var wsBytes int64
+	for _, ws := range t.webSeeds {
+		st := &state.SourceStat{
+			Source:    ws.url,
+			BytesRead: atomic.LoadInt64(&ws.bytes),
+		}
+		wsMu.Lock()
+		peer := ws.peer
+		wsMu.Unlock()
+		if peer != nil {
+			st.DownloadSpeed = peer.DownloadRate()
+		}
+		wsBytes += st.BytesRead
+		stats = append(stats, st)
+	}
+	peers.BytesRead = usefulBytes - wsBytes
+	if peers.BytesRead < 0 {
+		peers.BytesRead = 0
+	}
+	return stats
+}
+
+// AddWebSeeds adds web seed urls to active and saved torrent
+func AddWebSeeds(hashHex string, urls []string) error {
+	var list []string
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		pu, err := url.Parse(u)
+		if err != nil || (pu.Scheme != "http" && pu.Scheme != "https") || pu.Host == "" {
+			return errors.New("wrong web seed url: " + u)
+		}
+		list = append(list, u)
+	}
+	if len(list) == 0 {
+		return errors.New("web seeds is empty")
+	}
+
+	hash := metainfo.NewHashFromHex(hashHex)
+	tor := bts.GetTorrent(hash)
+	db := sets.GetTorrent(hash)
+	if tor == nil && db == nil {
+		return errors.New("torrent not found")
+	}
+	if tor != nil {
+		tor.AddWebSeeds(list)
+	}
+	if db != nil {
+		changed := false
+		for _, u := range list {
+			if !contains(db.Webseeds, u) {
+				db.Webseeds = append(db.Webseeds, u)
+				changed = true
+			}
+		}
+		if changed {
+			sets.AddTorrent(db)
+		}
+	}
+	torrLog.Info("add web seeds", "hash", hashHex, "urls", strings.Join(list, " "))
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
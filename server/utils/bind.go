@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"errors"
+	"net"
+)
+
+// ResolveBind returns local ip of bind setting, it may be ip or interface name.
+// Empty bind means all interfaces and returns empty ip.
+func ResolveBind(bind string) (string, error) {
+	if bind == "" {
+		return "", nil
+	}
+	if ip := net.ParseIP(bind); ip != nil {
+		if ip.IsUnspecified() {
+			return ip.String(), nil
+		}
+		if _, err := interfaceByIP(ip); err != nil {
+			return "", err
+		}
+		return ip.String(), nil
+	}
+	iface, err := net.InterfaceByName(bind)
+	if err != nil {
+		return "", errors.New("bind " + bind + ": interface not found")
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		return "", errors.New("bind " + bind + ": interface is down")
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", errors.New("bind " + bind + ": " + err.Error())
+	}
+	var ip6 net.IP
+	for _, addr := range addrs {
+		ip := addrIP(addr)
+		if ip == nil || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		if ip.To4() != nil {
+			return ip.String(), nil
+		}
+		if ip6 == nil {
+			ip6 = ip
+		}
+	}
+	if ip6 != nil {
+		return ip6.String(), nil
+	}
+	return "", errors.New("bind " + bind + ": interface has no address")
+}
+
+// BindInterface returns interface of bind setting, nil for all interfaces
+func BindInterface(bind string) (*net.Interface, error) {
+	if bind == "" {
+		return nil, nil
+	}
+	if ip := net.ParseIP(bind); ip != nil {
+		if ip.IsUnspecified() {
+			return nil, nil
+		}
+		return interfaceByIP(ip)
+	}
+	iface, err := net.InterfaceByName(bind)
+	if err != nil {
+		return nil, errors.New("bind " + bind + ": interface not found")
+	}
+	return iface, nil
+}
+
+func interfaceByIP(ip net.IP) (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if addrIP(addr).Equal(ip) {
+				i := iface
+				return &i, nil
+			}
+		}
+	}
+	return nil, errors.New("bind " + ip.String() + ": address not found on local interfaces")
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch v := addr.(type) {
+	case *net.IPNet:
+		return v.IP
+	case *net.IPAddr:
+		return v.IP
+	}
+	return nil
+}
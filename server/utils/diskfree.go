@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package utils
+
+import "syscall"
+
+// DiskFree returns bytes available to user on disk of path
+func DiskFree(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,363 @@
+package watch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"server/log"
+	"server/settings"
+	"server/torr"
+	"server/web/api/utils"
+)
+
+const (
+	PolicyDelete = "delete"
+	PolicyKeep   = "keep"
+	PolicyMove   = "move"
+
+	StateLoading = "loading"
+	StateDone    = "done"
+	StateFailed  = "failed"
+
+	// per-folder options, inherited by subfolders
+	OptionsFile = ".torrserver.json"
+	DoneDir     = ".done"
+)
+
+type Options struct {
+	Policy   string `json:"policy,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+type Entry struct {
+	Path     string   `json:"path"`
+	Category string   `json:"category,omitempty"`
+	State    string   `json:"state"`
+	Error    string   `json:"error,omitempty"`
+	Hashes   []string `json:"hashes,omitempty"`
+	Time     int64    `json:"time"`
+
+	modTime time.Time
+	size    int64
+}
+
+var (
+	root      string
+	defPolicy = PolicyDelete
+	entries   = make(map[string]*Entry)
+	timers    = make(map[string]*time.Timer)
+	mu        sync.Mutex
+)
+
+var watchLog = log.Component("watch")
+
+// Start watches dir and subdirs for .torrent, .magnet and .txt files
+func Start(dir, policy string) {
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		path = dir
+	}
+	root = path
+	if policy != "" {
+		defPolicy = policy
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		watchLog.Error("error watch torrents dir", "error", err)
+		return
+	}
+	watchLog.Info("watch torrents dir", "path", root, "policy", defPolicy)
+	// wait for bt client
+	time.Sleep(5 * time.Second)
+	addDir(watcher, root)
+
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+				continue
+			}
+			if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+				if ev.Op&fsnotify.Create != 0 && !isHidden(fi.Name()) {
+					addDir(watcher, ev.Name)
+				}
+				continue
+			}
+			schedule(ev.Name)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			watchLog.Error("error watch torrents dir", "error", err)
+		}
+	}
+}
+
+func List() []*Entry {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]*Entry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Time > list[j].Time
+	})
+	return list
+}
+
+func addDir(watcher *fsnotify.Watcher, dir string) {
+	filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if fi.IsDir() {
+			if path != dir && isHidden(fi.Name()) {
+				return filepath.SkipDir
+			}
+			if err = watcher.Add(path); err != nil {
+				watchLog.Error("error watch dir", "path", path, "error", err)
+			}
+			return nil
+		}
+		schedule(path)
+		return nil
+	})
+}
+
+// schedule waits until file stops changing
+func schedule(path string) {
+	if isHidden(filepath.Base(path)) || !isSupported(path) {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if tm, ok := timers[path]; ok {
+		tm.Reset(2 * time.Second)
+		return
+	}
+	timers[path] = time.AfterFunc(2*time.Second, func() {
+		mu.Lock()
+		delete(timers, path)
+		mu.Unlock()
+		process(path)
+	})
+}
+
+func process(path string) {
+	fi, err := os.Stat(path)
+	if err != nil || fi.IsDir() {
+		return
+	}
+
+	mu.Lock()
+	// same file already processed, don`t retry until it changed
+	if e, ok := entries[path]; ok && e.modTime.Equal(fi.ModTime()) && e.size == fi.Size() {
+		mu.Unlock()
+		return
+	}
+	opts := folderOptions(filepath.Dir(path))
+	entry := &Entry{
+		Path:     path,
+		Category: opts.Category,
+		State:    StateLoading,
+		Time:     time.Now().Unix(),
+		modTime:  fi.ModTime(),
+		size:     fi.Size(),
+	}
+	entries[path] = entry
+	mu.Unlock()
+
+	links, err := readLinks(path)
+	if err == nil && len(links) == 0 {
+		err = errors.New("links not found")
+	}
+	if err != nil {
+		finish(entry, opts, []string{err.Error()})
+		return
+	}
+
+	var wg sync.WaitGroup
+	var muErr sync.Mutex
+	var errs []string
+	for _, link := range links {
+		wg.Add(1)
+		// callbacks of metadata queue may come after fail of link
+		var once sync.Once
+		done := func() {
+			once.Do(wg.Done)
+		}
+		fail := func(link, reason string) {
+			once.Do(func() {
+				muErr.Lock()
+				errs = append(errs, link+": "+reason)
+				muErr.Unlock()
+				wg.Done()
+			})
+		}
+		spec, err := utils.ParseLink(link)
+		if err != nil {
+			fail(link, err.Error())
+			continue
+		}
+		hash := spec.InfoHash.HexString()
+		mu.Lock()
+		entry.Hashes = append(entry.Hashes, hash)
+		mu.Unlock()
+		if settings.GetTorrent(spec.InfoHash) != nil {
+			done()
+			continue
+		}
+		tor, err := torr.AddTorrent(spec, "", "", "")
+		if err != nil {
+			fail(link, err.Error())
+			continue
+		}
+		tor.Category = entry.Category
+		if so := utils.ParseSelectOnly(link); len(so) > 0 {
+			tor.SelectOnly = so
+		}
+		queued := torr.ResolveMeta(tor, func(tor *torr.Torrent) {
+			if tor.Title == "" {
+				tor.Title = tor.Name()
+			}
+			torr.SaveTorrentToDB(tor)
+			torr.DropTorrent(hash)
+			done()
+		}, func(reason string) {
+			fail(link, reason)
+		})
+		if !queued {
+			fail(link, "torrent not added")
+		}
+	}
+	wg.Wait()
+	finish(entry, opts, errs)
+}
+
+func finish(entry *Entry, opts *Options, errs []string) {
+	mu.Lock()
+	entry.Time = time.Now().Unix()
+	if len(errs) > 0 {
+		entry.State = StateFailed
+		entry.Error = strings.Join(errs, "; ")
+		watchLog.Error("error load torrents", "path", entry.Path, "error", entry.Error)
+	} else {
+		entry.State = StateDone
+	}
+	mu.Unlock()
+	if entry.State != StateDone {
+		return
+	}
+
+	switch opts.Policy {
+	case PolicyKeep:
+	case PolicyMove:
+		dir := filepath.Join(filepath.Dir(entry.Path), DoneDir)
+		os.MkdirAll(dir, 0777)
+		if err := os.Rename(entry.Path, filepath.Join(dir, filepath.Base(entry.Path))); err != nil {
+			watchLog.Error("error move loaded file", "path", entry.Path, "error", err)
+		}
+	default:
+		os.Remove(entry.Path)
+	}
+}
+
+// readLinks returns torrent links of file, one per line for .magnet and .txt
+func readLinks(path string) ([]string, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".torrent" {
+		return []string{"file://" + path}, nil
+	}
+	ff, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer ff.Close()
+	var links []string
+	scanner := bufio.NewScanner(ff)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		lower := strings.ToLower(line)
+		if strings.HasPrefix(lower, "magnet:") || strings.HasPrefix(lower, "http://") ||
+			strings.HasPrefix(lower, "https://") || isHash(line) {
+			links = append(links, line)
+		}
+	}
+	return links, scanner.Err()
+}
+
+// folderOptions merges options from root to dir, first level folder name is
+// default category
+func folderOptions(dir string) *Options {
+	opts := &Options{Policy: defPolicy}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return opts
+	}
+	parts := []string{}
+	if rel != "." {
+		parts = strings.Split(rel, string(filepath.Separator))
+		opts.Category = parts[0]
+	}
+	path := root
+	for i := 0; i <= len(parts); i++ {
+		if i > 0 {
+			path = filepath.Join(path, parts[i-1])
+		}
+		buf, err := ioutil.ReadFile(filepath.Join(path, OptionsFile))
+		if err != nil {
+			continue
+		}
+		var o Options
+		if err = json.Unmarshal(buf, &o); err != nil {
+			watchLog.Error("error read options", "path", filepath.Join(path, OptionsFile), "error", err)
+			continue
+		}
+		if o.Policy != "" {
+			opts.Policy = o.Policy
+		}
+		if o.Category != "" {
+			opts.Category = o.Category
+		}
+	}
+	return opts
+}
+
+func isSupported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".torrent", ".magnet", ".txt":
+		return true
+	}
+	return false
+}
+
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+func isHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"server/dlna"
+	"server/log"
+	sets "server/settings"
+	"server/torr"
+	"server/web/auth"
+)
+
+// http://127.0.0.1:8090/backup
+func backup(c *gin.Context) {
+	// backup has passwords of accounts
+	if !sets.IsAdmin(c.GetString(gin.AuthUserKey)) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	var buf bytes.Buffer
+	err := sets.WriteBackup(&buf)
+	if err != nil {
+		log.TLogln("error create backup:", err)
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	name := "torrserver-" + time.Now().Format("20060102-150405") + ".zip"
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Data(200, "application/zip", buf.Bytes())
+}
+
+// multipart form: file, mode=merge|replace, dry_run
+func restore(c *gin.Context) {
+	if !sets.IsAdmin(c.GetString(gin.AuthUserKey)) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	defer form.RemoveAll()
+
+	mode := ""
+	if len(form.Value["mode"]) > 0 {
+		mode = form.Value["mode"][0]
+	}
+	dryRun := len(form.Value["dry_run"]) > 0 && form.Value["dry_run"][0] != "false"
+	if !dryRun && sets.ReadOnly {
+		c.AbortWithError(http.StatusForbidden, errors.New("db in read-only mode"))
+		return
+	}
+
+	var data []byte
+	for _, file := range form.File {
+		ff, err := file[0].Open()
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		data, err = ioutil.ReadAll(ff)
+		ff.Close()
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		break
+	}
+	if len(data) == 0 {
+		c.AbortWithError(http.StatusBadRequest, errors.New("backup file is empty"))
+		return
+	}
+
+	bk, err := sets.ReadBackup(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		log.TLogln("error read backup:", err)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	rep, err := sets.RestoreBackup(bk, mode, dryRun)
+	if err != nil {
+		log.TLogln("error restore backup:", err)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if rep.Settings && !dryRun {
+		torr.SetSettings(sets.BTsets)
+		dlna.Stop()
+		if sets.BTsets.EnableDLNA {
+			dlna.Start()
+		}
+	}
+	if !dryRun && bk.Accounts != nil {
+		auth.ReloadAccounts()
+	}
+	c.JSON(200, rep)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 
+	sets "server/settings"
 	"server/torr"
 
 	"github.com/gin-gonic/gin"
@@ -38,7 +39,7 @@ func getCache(req cacheReqJS, c *gin.Context) {
 	}
 	tor := torr.GetTorrent(req.Hash)
 
-	if tor != nil {
+	if tor != nil && sets.UserHasTorrent(c.GetString(gin.AuthUserKey), tor.Hash().HexString()) {
 		st := tor.CacheState()
 		if st == nil {
 			c.JSON(200, struct{}{})
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	sets "server/settings"
+	"server/torr"
+)
+
+// Action: create, list
+type createReqJS struct {
+	requestI
+	Path        string   `json:"path,omitempty"`         // in content root
+	PieceLength int64    `json:"piece_length,omitempty"` // in bytes, 0 - auto
+	Trackers    []string `json:"trackers,omitempty"`
+	Comment     string   `json:"comment,omitempty"`
+	Title       string   `json:"title,omitempty"`
+}
+
+func torrentCreate(c *gin.Context) {
+	var req createReqJS
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	switch req.Action {
+	case "list":
+		c.JSON(200, torr.ListCreateJobs())
+		return
+	case "create":
+		if req.Path == "" {
+			c.AbortWithError(http.StatusBadRequest, errors.New("path is empty"))
+			return
+		}
+		user := c.GetString(gin.AuthUserKey)
+		job, err := torr.StartCreate(req.Path, req.PieceLength, req.Trackers, req.Comment, req.Title, func(hash string) {
+			sets.AddUserTorrent(user, hash)
+		})
+		if err == torr.ErrOutOfRoot {
+			c.AbortWithError(http.StatusForbidden, err)
+			return
+		}
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+	c.AbortWithError(http.StatusBadRequest, errors.New("action is empty"))
+}
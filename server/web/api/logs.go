@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"server/log"
+)
+
+// logs returns recent log entries from memory
+// http://127.0.0.1:8090/logs?level=warn&component=dlna&hash=...&client=...&q=...&since=100&limit=200
+func logs(c *gin.Context) {
+	since, _ := strconv.ParseInt(c.Query("since"), 10, 64)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	c.JSON(200, log.Recent(log.Filter{
+		Since:     since,
+		Level:     c.Query("level"),
+		Component: c.Query("component"),
+		Hash:      c.Query("hash"),
+		Client:    c.Query("client"),
+		Query:     c.Query("q"),
+		Limit:     limit,
+	}))
+}
+
+// Action: rotate
+type logsReqJS struct {
+	requestI
+}
+
+func logsAction(c *gin.Context) {
+	var req logsReqJS
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	switch req.Action {
+	case "rotate":
+		if err = log.Rotate(); err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		c.Status(200)
+	default:
+		c.AbortWithError(http.StatusBadRequest, errors.New("unknown action"))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,13 +18,15 @@ import (
 	"server/torr"
 	"server/torr/state"
 	"server/utils"
+	"server/web/auth"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 )
 
 func allPlayList(c *gin.Context) {
-	torrs := torr.ListTorrent()
+	user := c.GetString(gin.AuthUserKey)
+	torrs := torr.ListUserTorrents(user)
 
 	host := utils.GetScheme(c) + "://" + c.Request.Host
 	list := "#EXTM3U\n"
@@ -31,7 +34,8 @@ func allPlayList(c *gin.Context) {
 	// fn=file.m3u fix forkplayer bug with end .m3u in link
 	for _, tr := range torrs {
 		list += "#EXTINF:0 type=\"playlist\"," + tr.Title + "\n"
-		list += host + "/stream/" + url.PathEscape(tr.Title) + ".m3u?link=" + tr.TorrentSpec.InfoHash.HexString() + "&m3u&fn=file.m3u\n"
+		list += host + "/stream/" + url.PathEscape(tr.Title) + ".m3u?link=" + tr.TorrentSpec.InfoHash.HexString() + "&m3u" +
+			auth.SignStream(user, tr.TorrentSpec.InfoHash.HexString(), "") + "&fn=file.m3u\n"
 		hash += tr.Hash().HexString()
 	}
 
@@ -49,7 +53,7 @@ func playList(c *gin.Context) {
 	}
 
 	tor := torr.GetTorrent(hash)
-	if tor == nil {
+	if tor == nil || !sets.UserHasTorrent(c.GetString(gin.AuthUserKey), tor.Hash().HexString()) {
 		c.AbortWithStatus(http.StatusNotFound)
 		return
 	}
@@ -63,7 +67,7 @@ func playList(c *gin.Context) {
 	}
 
 	host := utils.GetScheme(c) + "://" + c.Request.Host
-	list := getM3uList(tor.Status(), host, fromlast)
+	list := getM3uList(tor.Status(), c.GetString(gin.AuthUserKey), host, fromlast)
 	list = "#EXTM3U\n" + list
 
 	sendM3U(c, tor.Name()+".m3u", tor.Hash().HexString(), list)
@@ -83,13 +87,19 @@ func sendM3U(c *gin.Context, name, hash string, m3u string) {
 	http.ServeContent(c.Writer, c.Request, name, time.Now(), bytes.NewReader([]byte(m3u)))
 }
 
-func getM3uList(tor *state.TorrentStatus, host string, fromLast bool) string {
+func getM3uList(tor *state.TorrentStatus, user, host string, fromLast bool) string {
 	m3u := ""
 	from := 0
+	var resume *sets.Viewed
 	if fromLast {
-		pos := searchLastPlayed(tor)
+		pos, last := searchLastPlayed(tor, user)
 		if pos != -1 {
 			from = pos
+			if last.Watched && pos+1 < len(tor.FileStats) {
+				from = pos + 1
+			} else if !last.Watched {
+				resume = last
+			}
 		}
 	}
 	for i, f := range tor.FileStats {
@@ -99,18 +109,27 @@ func getM3uList(tor *state.TorrentStatus, host string, fromLast bool) string {
 				if fn == "" {
 					fn = f.Path
 				}
-				m3u += "#EXTINF:0," + fn + "\n"
+				duration := 0
+				if f.Media != nil {
+					duration = int(f.Media.Duration)
+				}
+				m3u += "#EXTINF:" + strconv.Itoa(duration) + "," + fn + "\n"
+				if resume != nil && resume.FileIndex == f.Id && resume.Position > 0 {
+					m3u += "#EXTVLCOPT:start-time=" + strconv.Itoa(int(resume.Position)) + "\n"
+				}
 				fileNamesakes := findFileNamesakes(tor.FileStats, f) //find external media with same name (audio/subtiles tracks)
 				if fileNamesakes != nil {
 					m3u += "#EXTVLCOPT:input-slave="         //include VLC option for external media
 					for _, namesake := range fileNamesakes { //include play-links to external media, with # splitter
 						sname := filepath.Base(namesake.Path)
-						m3u += host + "/stream/" + url.PathEscape(sname) + "?link=" + tor.Hash + "&index=" + fmt.Sprint(namesake.Id) + "&play#"
+						m3u += host + "/stream/" + url.PathEscape(sname) + "?link=" + tor.Hash + "&index=" + fmt.Sprint(namesake.Id) + "&play" +
+							auth.SignStream(user, tor.Hash, fmt.Sprint(namesake.Id)) + "#"
 					}
 					m3u += "\n"
 				}
 				name := filepath.Base(f.Path)
-				m3u += host + "/stream/" + url.PathEscape(name) + "?link=" + tor.Hash + "&index=" + fmt.Sprint(f.Id) + "&play\n"
+				m3u += host + "/stream/" + url.PathEscape(name) + "?link=" + tor.Hash + "&index=" + fmt.Sprint(f.Id) + "&play" +
+					auth.SignStream(user, tor.Hash, fmt.Sprint(f.Id)) + "\n"
 			}
 		}
 	}
@@ -131,25 +150,24 @@ func findFileNamesakes(files []*state.TorrentFileStat, file *state.TorrentFileSt
 	return namesakes
 }
 
-func searchLastPlayed(tor *state.TorrentStatus) int {
-	viewed := sets.ListViewed(tor.Hash)
+// searchLastPlayed returns position in file stats of last updated viewed file
+func searchLastPlayed(tor *state.TorrentStatus, user string) (int, *sets.Viewed) {
+	viewed := sets.ListViewed(user, tor.Hash)
 	if len(viewed) == 0 {
-		return -1
+		return -1, nil
 	}
 	sort.Slice(viewed, func(i, j int) bool {
+		if viewed[i].Updated != viewed[j].Updated {
+			return viewed[i].Updated > viewed[j].Updated
+		}
 		return viewed[i].FileIndex > viewed[j].FileIndex
 	})
 
-	lastViewedIndex := viewed[0].FileIndex
-
 	for i, stat := range tor.FileStats {
-		if stat.Id == lastViewedIndex {
-			if i >= len(tor.FileStats) {
-				return -1
-			}
-			return i
+		if stat.Id == viewed[0].FileIndex {
+			return i, viewed[0]
 		}
 	}
 
-	return -1
+	return -1, nil
 }
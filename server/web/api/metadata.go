@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	sets "server/settings"
+	"server/torr"
+	"server/torr/state"
+)
+
+// Action: list, retry, cancel
+type metaReqJS struct {
+	requestI
+	Hash string `json:"hash,omitempty"`
+}
+
+func metadata(c *gin.Context) {
+	var req metaReqJS
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	switch req.Action {
+	case "list":
+		list := torr.ListMetaJobs()
+		if visible := sets.UserTorrents(c.GetString(gin.AuthUserKey)); visible != nil {
+			var ret []*state.MetaJobStatus
+			for _, job := range list {
+				if visible[job.Hash] {
+					ret = append(ret, job)
+				}
+			}
+			list = ret
+		}
+		c.JSON(200, list)
+		return
+	case "retry", "cancel":
+		if req.Hash == "" {
+			c.AbortWithError(http.StatusBadRequest, errors.New("hash is empty"))
+			return
+		}
+		if !sets.UserHasTorrent(c.GetString(gin.AuthUserKey), strings.ToLower(req.Hash)) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if req.Action == "retry" {
+			err = torr.RetryMeta(req.Hash)
+		} else {
+			err = torr.CancelMeta(req.Hash)
+		}
+		if err != nil {
+			c.AbortWithError(http.StatusNotFound, err)
+			return
+		}
+		c.Status(200)
+		return
+	}
+	c.AbortWithError(http.StatusBadRequest, errors.New("action is empty"))
+}
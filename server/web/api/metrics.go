@@ -0,0 +1,17 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"server/metrics"
+	"server/torr"
+)
+
+// metricsPage returns metrics in prometheus text format
+func metricsPage(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(200)
+	w := metrics.NewWriter(c.Writer)
+	torr.WriteMetrics(w)
+	metrics.WriteGlobal(w)
+}
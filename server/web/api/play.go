@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/anacrolix/torrent/metainfo"
 	"github.com/gin-gonic/gin"
 
+	sets "server/settings"
 	"server/torr"
 	"server/torr/state"
 	"server/web/api/utils"
@@ -15,7 +17,7 @@ import (
 func play(c *gin.Context) {
 	hash := c.Param("hash")
 	indexStr := c.Param("id")
-	notAuth := c.GetBool("not_auth")
+	signed := c.GetBool("signed")
 
 	if hash == "" || indexStr == "" {
 		c.AbortWithError(http.StatusNotFound, errors.New("link should not be empty"))
@@ -28,10 +30,13 @@ func play(c *gin.Context) {
 		return
 	}
 
+	if hiddenTorrent(c, spec.InfoHash) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
 	tor := torr.GetTorrent(spec.InfoHash.HexString())
-	if tor == nil && notAuth {
-		c.Header("WWW-Authenticate", "Basic realm=Authorization Required")
-		c.AbortWithStatus(http.StatusUnauthorized)
+	if tor == nil && signed {
+		c.AbortWithStatus(http.StatusNotFound)
 		return
 	}
 
@@ -54,20 +59,31 @@ func play(c *gin.Context) {
 	}
 
 	// find file
-	index := -1
-	if len(tor.Files()) == 1 {
-		index = 1
-	} else {
-		ind, err := strconv.Atoi(indexStr)
-		if err == nil {
-			index = ind
-		}
-	}
+	index := fileIndex(tor, indexStr)
 	if index == -1 { // if file index not set and play file exec
 		c.AbortWithError(http.StatusBadRequest, errors.New("\"index\" is wrong"))
 		return
 	}
 
-	tor.Stream(index, c.Request, c.Writer)
+	tor.Stream(index, c.GetString(gin.AuthUserKey), c.Request, c.Writer)
 	return
 }
+
+// hiddenTorrent checks that torrent is saved in db and user doesn't have it,
+// torrents which aren't saved are streamed by anyone with link
+func hiddenTorrent(c *gin.Context, hash metainfo.Hash) bool {
+	return !sets.UserHasTorrent(c.GetString(gin.AuthUserKey), hash.HexString()) && sets.GetTorrent(hash) != nil
+}
+
+// fileIndex parses index of file, file of single file torrent is found by
+// any index if there's no virtual file with it, -1 if index is wrong
+func fileIndex(tor *torr.Torrent, indexStr string) int {
+	index, err := strconv.Atoi(indexStr)
+	if len(tor.Files()) == 1 && (err != nil || !tor.HasFile(index)) {
+		return 1
+	}
+	if err != nil {
+		return -1
+	}
+	return index
+}
@@ -2,7 +2,6 @@ package api
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	sets "server/settings"
@@ -20,6 +19,14 @@ func SetupRoute(route *gin.RouterGroup) {
 
 	route.POST("/torrents", torrents)
 	route.POST("/torrent/upload", torrentUpload)
+	route.POST("/torrent/create", torrentCreate)
+	route.GET("/torrent/:hash", torrentFile)
+	route.GET("/magnet/:hash", magnet)
+	route.POST("/metadata", metadata)
+	route.GET("/watch", watchList)
+	route.GET("/metrics", metricsPage)
+	route.GET("/logs", logs)
+	route.POST("/logs", logsAction)
 
 	route.POST("/cache", cache)
 
@@ -32,13 +39,19 @@ func SetupRoute(route *gin.RouterGroup) {
 	route.HEAD("/play/:hash/:id", play)
 	route.GET("/play/:hash/:id", play)
 
+	route.GET("/subtitles/:hash/:id", subtitlesVTT)
+
 	route.POST("/viewed", viewed)
+	route.POST("/sessions", sessions)
 
 	route.GET("/playlistall/all.m3u", allPlayList)
 	route.GET("/playlist", playList)
 	route.GET("/playlist/*fname", playList)
 
 	route.GET("/download/:size", download)
+
+	route.GET("/backup", backup)
+	route.POST("/restore", restore)
 }
 
 func shutdown(c *gin.Context) {
@@ -46,9 +59,7 @@ func shutdown(c *gin.Context) {
 		c.Status(http.StatusForbidden)
 		return
 	}
+	// web server finishes this request before stop
+	torr.Shutdown()
 	c.Status(200)
-	go func() {
-		time.Sleep(1000)
-		torr.Shutdown()
-	}()
 }
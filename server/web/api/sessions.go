@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	sets "server/settings"
+	"server/torr"
+)
+
+// Action: list, kill, throttle
+type sessionsReqJS struct {
+	requestI
+	Id int64 `json:"id,omitempty"`
+	// Limit of throttle in bytes/s, 0 removes limit
+	Limit int64 `json:"limit,omitempty"`
+}
+
+func sessions(c *gin.Context) {
+	var req sessionsReqJS
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// users see only own sessions
+	user := c.GetString(gin.AuthUserKey)
+	list := torr.ListSessions()
+	if !sets.IsAdmin(user) {
+		own := list[:0]
+		for _, s := range list {
+			if s.User == user {
+				own = append(own, s)
+			}
+		}
+		list = own
+	}
+	found := false
+	for _, s := range list {
+		found = found || s.Id == req.Id
+	}
+
+	switch req.Action {
+	case "list":
+		c.JSON(200, list)
+	case "kill":
+		if !found || !torr.KillSession(req.Id) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Status(200)
+	case "throttle":
+		if req.Limit < 0 {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		if !found || !torr.ThrottleSession(req.Id, req.Limit) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Status(200)
+	default:
+		c.AbortWithStatus(http.StatusBadRequest)
+	}
+}
@@ -6,12 +6,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"server/dlna"
+	"server/log"
+	"server/proxy"
 
 	sets "server/settings"
 	"server/torr"
+	"server/utils"
 )
 
-//Action: get, set, def
+//Action: get, set, def, rotatesecret
 type setsReqJS struct {
 	requestI
 	Sets *sets.BTSets `json:"sets,omitempty"`
@@ -29,6 +32,29 @@ func settings(c *gin.Context) {
 		c.JSON(200, sets.BTsets)
 		return
 	} else if req.Action == "set" {
+		if req.Sets == nil {
+			c.AbortWithError(http.StatusBadRequest, errors.New("sets is empty"))
+			return
+		}
+		err = proxy.Check(req.Sets.ProxyURL, req.Sets.ProxyTrackers, req.Sets.ProxyPeers, req.Sets.ProxyHTTP)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		err = log.CheckLevels(req.Sets.LogLevel, req.Sets.LogLevels)
+		if err == nil {
+			err = log.CheckFormat(req.Sets.LogFormat)
+		}
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		for _, bind := range []string{req.Sets.WebBind, req.Sets.PeersBind, req.Sets.DLNABind} {
+			if _, err = utils.ResolveBind(bind); err != nil {
+				c.AbortWithError(http.StatusBadRequest, err)
+				return
+			}
+		}
 		torr.SetSettings(req.Sets)
 		dlna.Stop()
 		if req.Sets.EnableDLNA {
@@ -41,6 +67,16 @@ func settings(c *gin.Context) {
 		dlna.Stop()
 		c.Status(200)
 		return
+	} else if req.Action == "rotatesecret" {
+		// signed stream links of all users are revoked, any user can do it
+		// if admins aren't set
+		if len(sets.Admins) > 0 && !sets.IsAdmin(c.GetString(gin.AuthUserKey)) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		sets.RotateStreamSecret()
+		c.Status(200)
+		return
 	}
 	c.AbortWithError(http.StatusBadRequest, errors.New("action is empty"))
 }
@@ -3,8 +3,8 @@ package api
 import (
 	"net/http"
 	"net/url"
-	"strconv"
 
+	sets "server/settings"
 	"server/torr"
 	"server/torr/state"
 	utils2 "server/utils"
@@ -16,6 +16,9 @@ import (
 
 // get stat
 // http://127.0.0.1:8090/stream/fname?link=...&stat
+// get buffer health and ready flag, preload runs in background
+// http://127.0.0.1:8090/stream/fname?link=...&index=1&ready
+// http://127.0.0.1:8090/stream/fname?link=...&index=1&ready&preload
 // get m3u
 // http://127.0.0.1:8090/stream/fname?link=...&index=1&m3u
 // http://127.0.0.1:8090/stream/fname?link=...&index=1&m3u&fromlast
@@ -32,6 +35,7 @@ func stream(c *gin.Context) {
 	indexStr := c.Query("index")
 	_, preload := c.GetQuery("preload")
 	_, stat := c.GetQuery("stat")
+	_, ready := c.GetQuery("ready")
 	_, save := c.GetQuery("save")
 	_, m3u := c.GetQuery("m3u")
 	_, fromlast := c.GetQuery("fromlast")
@@ -39,13 +43,10 @@ func stream(c *gin.Context) {
 	title := c.Query("title")
 	poster := c.Query("poster")
 	data := ""
-	notAuth := c.GetBool("not_auth")
+	// signed link only plays, preloads file or gets playlist of torrent in db
+	signed := c.GetBool("signed")
 
-	if notAuth && (play || m3u) {
-		streamNoAuth(c)
-		return
-	}
-	if notAuth {
+	if signed && (save || stat || !(play || m3u || ready || preload)) {
 		c.Header("WWW-Authenticate", "Basic realm=Authorization Required")
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
@@ -66,7 +67,16 @@ func stream(c *gin.Context) {
 		return
 	}
 
+	// save adds torrent to library of user
+	if !save && hiddenTorrent(c, spec.InfoHash) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
 	tor := torr.GetTorrent(spec.InfoHash.HexString())
+	if tor == nil && signed {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
 	if tor != nil {
 		title = tor.Title
 		poster = tor.Poster
@@ -78,6 +88,9 @@ func stream(c *gin.Context) {
 			c.AbortWithError(http.StatusInternalServerError, err)
 			return
 		}
+		if so := utils.ParseSelectOnly(link); len(so) > 0 {
+			tor.SelectOnly = so
+		}
 	}
 
 	if !tor.GotInfo() {
@@ -92,23 +105,29 @@ func stream(c *gin.Context) {
 	// save to db
 	if save {
 		torr.SaveTorrentToDB(tor)
+		sets.AddUserTorrent(c.GetString(gin.AuthUserKey), tor.Hash().HexString())
 		c.Status(200) // only set status, not return
 	}
 
 	// find file
-	index := -1
-	if len(tor.Files()) == 1 {
-		index = 1
-	} else {
-		ind, err := strconv.Atoi(indexStr)
-		if err == nil {
-			index = ind
-		}
-	}
-	if index == -1 && play { // if file index not set and play file exec
+	index := fileIndex(tor, indexStr)
+	if index == -1 && (play || ready) { // if file index not set and play file exec
 		c.AbortWithError(http.StatusBadRequest, errors.New("\"index\" is empty or wrong"))
 		return
 	}
+	// return buffer health, client waits for ready before playback
+	if ready {
+		if preload {
+			go torr.Preload(tor, index)
+		}
+		buf := tor.FileBuffer(index)
+		if buf == nil {
+			c.AbortWithError(http.StatusNotFound, errors.New("file not found"))
+			return
+		}
+		c.JSON(200, buf)
+		return
+	}
 	// preload torrent
 	if preload {
 		torr.Preload(tor, index)
@@ -120,96 +139,13 @@ func stream(c *gin.Context) {
 	} else
 	// return m3u if query
 	if m3u {
-		m3ulist := "#EXTM3U\n" + getM3uList(tor.Status(), utils2.GetScheme(c)+"://"+c.Request.Host, fromlast)
+		m3ulist := "#EXTM3U\n" + getM3uList(tor.Status(), c.GetString(gin.AuthUserKey), utils2.GetScheme(c)+"://"+c.Request.Host, fromlast)
 		sendM3U(c, tor.Name()+".m3u", tor.Hash().HexString(), m3ulist)
 		return
 	} else
 	// return play if query
 	if play {
-		tor.Stream(index, c.Request, c.Writer)
-		return
-	}
-}
-
-func streamNoAuth(c *gin.Context) {
-	link := c.Query("link")
-	indexStr := c.Query("index")
-	_, preload := c.GetQuery("preload")
-	_, m3u := c.GetQuery("m3u")
-	_, fromlast := c.GetQuery("fromlast")
-	_, play := c.GetQuery("play")
-	title := c.Query("title")
-	poster := c.Query("poster")
-	data := ""
-
-	if link == "" {
-		c.AbortWithError(http.StatusBadRequest, errors.New("link should not be empty"))
+		tor.Stream(index, c.GetString(gin.AuthUserKey), c.Request, c.Writer)
 		return
 	}
-
-	link, _ = url.QueryUnescape(link)
-
-	spec, err := utils.ParseLink(link)
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
-		return
-	}
-
-	tor := torr.GetTorrent(spec.InfoHash.HexString())
-	if tor == nil {
-		c.Header("WWW-Authenticate", "Basic realm=Authorization Required")
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
-
-	title = tor.Title
-	poster = tor.Poster
-	data = tor.Data
-
-	if tor.Stat == state.TorrentInDB {
-		tor, err = torr.AddTorrent(spec, title, poster, data)
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			return
-		}
-	}
-
-	if !tor.GotInfo() {
-		c.AbortWithError(http.StatusInternalServerError, errors.New("timeout connection torrent"))
-		return
-	}
-
-	// find file
-	index := -1
-	if len(tor.Files()) == 1 {
-		index = 1
-	} else {
-		ind, err := strconv.Atoi(indexStr)
-		if err == nil {
-			index = ind
-		}
-	}
-	if index == -1 && play { // if file index not set and play file exec
-		c.AbortWithError(http.StatusBadRequest, errors.New("\"index\" is empty or wrong"))
-		return
-	}
-	// preload torrent
-	if preload {
-		torr.Preload(tor, index)
-	}
-
-	// return m3u if query
-	if m3u {
-		m3ulist := "#EXTM3U\n" + getM3uList(tor.Status(), utils2.GetScheme(c)+"://"+c.Request.Host, fromlast)
-		sendM3U(c, tor.Name()+".m3u", tor.Hash().HexString(), m3ulist)
-		return
-	} else
-	// return play if query
-	if play {
-		tor.Stream(index, c.Request, c.Writer)
-		return
-	}
-	c.Header("WWW-Authenticate", "Basic realm=Authorization Required")
-	c.AbortWithStatus(http.StatusUnauthorized)
-
 }
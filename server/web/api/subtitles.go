@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"server/subtitles"
+	"server/torr"
+	"server/torr/state"
+	"server/web/api/utils"
+)
+
+// http://127.0.0.1:8090/subtitles/hash/3.vtt?offset=-1.5
+func subtitlesVTT(c *gin.Context) {
+	hash := c.Param("hash")
+	id, err := strconv.Atoi(strings.TrimSuffix(c.Param("id"), ".vtt"))
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, errors.New("\"id\" is wrong"))
+		return
+	}
+	opts := subtitles.Options{Charset: c.Query("charset")}
+	if s := c.Query("offset"); s != "" {
+		sec, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, errors.New("\"offset\" is wrong"))
+			return
+		}
+		opts.Offset = time.Duration(sec * float64(time.Second))
+	}
+	if s := c.Query("fps"); s != "" {
+		if opts.FPS, err = strconv.ParseFloat(s, 64); err != nil {
+			c.AbortWithError(http.StatusBadRequest, errors.New("\"fps\" is wrong"))
+			return
+		}
+	}
+
+	spec, err := utils.ParseLink(hash)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if hiddenTorrent(c, spec.InfoHash) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	tor := torr.GetTorrent(spec.InfoHash.HexString())
+	if tor == nil {
+		c.AbortWithError(http.StatusNotFound, errors.New("torrent not found"))
+		return
+	}
+	if tor.Stat == state.TorrentInDB {
+		tor, err = torr.AddTorrent(spec, tor.Title, tor.Poster, tor.Data)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if !tor.GotInfo() {
+		c.AbortWithError(http.StatusInternalServerError, errors.New("timeout connection torrent"))
+		return
+	}
+
+	path, data, err := tor.ReadFile(id)
+	if err != nil {
+		c.AbortWithError(http.StatusNotFound, err)
+		return
+	}
+	if !subtitles.IsSubtitle(path) {
+		c.AbortWithError(http.StatusUnsupportedMediaType, subtitles.ErrUnsupported)
+		return
+	}
+	vtt, err := subtitles.ToVTT(data, filepath.Ext(path), opts)
+	if errors.Is(err, subtitles.ErrUnsupported) {
+		c.AbortWithError(http.StatusUnsupportedMediaType, err)
+		return
+	}
+	if err != nil {
+		// wrong charset name
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	c.Data(200, "text/vtt; charset=utf-8", vtt)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	sets "server/settings"
+	"server/torr"
+	"server/torr/state"
+)
+
+// http://127.0.0.1:8090/torrent/hash.torrent
+func torrentFile(c *gin.Context) {
+	tor := getTorrentByParam(c)
+	if tor == nil {
+		return
+	}
+
+	mi, err := tor.BuildMetaInfo()
+	if err != nil && tor.Stat == state.TorrentInDB {
+		// saved without info, load it from peers
+		if tor = torr.LoadTorrent(tor); tor != nil {
+			mi, err = tor.BuildMetaInfo()
+		}
+	}
+	if err != nil || mi == nil {
+		if err == nil {
+			err = errors.New("error get torrent info")
+		}
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = mi.Write(&buf); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	name := tor.Title
+	if name == "" {
+		name = tor.Hash().HexString()
+	}
+	name = strings.NewReplacer("/", "_", "\\", "_", "\"", "'").Replace(name)
+	c.Header("Content-Disposition", `attachment; filename="`+name+`.torrent"`)
+	c.Data(200, "application/x-bittorrent", buf.Bytes())
+}
+
+// http://127.0.0.1:8090/magnet/hash
+func magnet(c *gin.Context) {
+	tor := getTorrentByParam(c)
+	if tor == nil {
+		return
+	}
+	c.String(200, tor.MagnetURI())
+}
+
+func getTorrentByParam(c *gin.Context) *torr.Torrent {
+	hash := strings.TrimSuffix(strings.ToLower(c.Param("hash")), ".torrent")
+	if len(hash) != 40 {
+		c.AbortWithError(http.StatusBadRequest, errors.New("wrong hash"))
+		return nil
+	}
+	tor := torr.GetTorrent(hash)
+	if tor == nil || !sets.UserHasTorrent(c.GetString(gin.AuthUserKey), hash) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return nil
+	}
+	return tor
+}
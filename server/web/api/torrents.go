@@ -15,7 +15,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-//Action: add, get, set, rem, list, drop
+//Action: add, get, set, rem, list, page, drop, webseeds
 type torrReqJS struct {
 	requestI
 	Link     string `json:"link,omitempty"`
@@ -24,6 +24,17 @@ type torrReqJS struct {
 	Poster   string `json:"poster,omitempty"`
 	Data     string `json:"data,omitempty"`
 	SaveToDB bool   `json:"save_to_db,omitempty"`
+	Category string `json:"category,omitempty"`
+	Order    string `json:"order,omitempty"`
+	Cursor   string `json:"cursor,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+
+	WebSeeds []string `json:"webseeds,omitempty"`
+}
+
+type torrPageJS struct {
+	Torrents []*state.TorrentStatus `json:"torrents"`
+	Next     string                 `json:"next,omitempty"`
 }
 
 func torrents(c *gin.Context) {
@@ -55,10 +66,18 @@ func torrents(c *gin.Context) {
 		{
 			listTorrent(req, c)
 		}
+	case "page":
+		{
+			pageTorrent(req, c)
+		}
 	case "drop":
 		{
 			dropTorrent(req, c)
 		}
+	case "webseeds":
+		{
+			addWebSeeds(req, c)
+		}
 
 	}
 }
@@ -84,13 +103,15 @@ func addTorrent(req torrReqJS, c *gin.Context) {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
+	set.AddUserTorrent(c.GetString(gin.AuthUserKey), tor.Hash().HexString())
+	if req.Category != "" {
+		tor.Category = req.Category
+	}
+	if so := utils.ParseSelectOnly(req.Link); len(so) > 0 {
+		tor.SelectOnly = so
+	}
 
-	go func() {
-		if !tor.GotInfo() {
-			log.TLogln("error add torrent:", "timeout connection get torrent info")
-			return
-		}
-
+	go torr.ResolveMeta(tor, func(tor *torr.Torrent) {
 		if tor.Title == "" {
 			tor.Title = torrSpec.DisplayName // prefer dn over name
 			tor.Title = strings.ReplaceAll(tor.Title, "rutor.info", "")
@@ -104,7 +125,7 @@ func addTorrent(req torrReqJS, c *gin.Context) {
 		if req.SaveToDB {
 			torr.SaveTorrentToDB(tor)
 		}
-	}()
+	}, nil)
 	// TODO: remove
 	if set.BTsets.EnableDLNA {
 		dlna.Stop()
@@ -118,6 +139,10 @@ func getTorrent(req torrReqJS, c *gin.Context) {
 		c.AbortWithError(http.StatusBadRequest, errors.New("hash is empty"))
 		return
 	}
+	if !set.UserHasTorrent(c.GetString(gin.AuthUserKey), req.Hash) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
 	tor := torr.GetTorrent(req.Hash)
 
 	if tor != nil {
@@ -133,6 +158,10 @@ func setTorrent(req torrReqJS, c *gin.Context) {
 		c.AbortWithError(http.StatusBadRequest, errors.New("hash is empty"))
 		return
 	}
+	if !set.UserHasTorrent(c.GetString(gin.AuthUserKey), req.Hash) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
 	torr.SetTorrent(req.Hash, req.Title, req.Poster, req.Data)
 	c.Status(200)
 }
@@ -142,7 +171,11 @@ func remTorrent(req torrReqJS, c *gin.Context) {
 		c.AbortWithError(http.StatusBadRequest, errors.New("hash is empty"))
 		return
 	}
-	torr.RemTorrent(req.Hash)
+	if !set.UserHasTorrent(c.GetString(gin.AuthUserKey), req.Hash) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	torr.RemUserTorrent(c.GetString(gin.AuthUserKey), req.Hash)
 	// TODO: remove
 	if set.BTsets.EnableDLNA {
 		dlna.Stop()
@@ -152,7 +185,7 @@ func remTorrent(req torrReqJS, c *gin.Context) {
 }
 
 func listTorrent(req torrReqJS, c *gin.Context) {
-	list := torr.ListTorrent()
+	list := torr.ListUserTorrents(c.GetString(gin.AuthUserKey))
 	if len(list) == 0 {
 		c.JSON(200, []*state.TorrentStatus{})
 		return
@@ -164,11 +197,43 @@ func listTorrent(req torrReqJS, c *gin.Context) {
 	c.JSON(200, stats)
 }
 
+func pageTorrent(req torrReqJS, c *gin.Context) {
+	list, next := torr.ListUserTorrentPage(c.GetString(gin.AuthUserKey), req.Order, req.Cursor, req.Limit)
+	page := torrPageJS{
+		Torrents: make([]*state.TorrentStatus, 0, len(list)),
+		Next:     next,
+	}
+	for _, tr := range list {
+		page.Torrents = append(page.Torrents, tr.Status())
+	}
+	c.JSON(200, page)
+}
+
 func dropTorrent(req torrReqJS, c *gin.Context) {
 	if req.Hash == "" {
 		c.AbortWithError(http.StatusBadRequest, errors.New("hash is empty"))
 		return
 	}
+	if !set.UserHasTorrent(c.GetString(gin.AuthUserKey), req.Hash) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
 	torr.DropTorrent(req.Hash)
 	c.Status(200)
 }
+
+func addWebSeeds(req torrReqJS, c *gin.Context) {
+	if req.Hash == "" {
+		c.AbortWithError(http.StatusBadRequest, errors.New("hash is empty"))
+		return
+	}
+	if !set.UserHasTorrent(c.GetString(gin.AuthUserKey), req.Hash) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if err := torr.AddWebSeeds(req.Hash, req.WebSeeds); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	c.Status(200)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"server/log"
+	sets "server/settings"
 	"server/torr"
 	"server/web/api/utils"
 )
@@ -52,13 +53,9 @@ func torrentUpload(c *gin.Context) {
 			log.TLogln("error upload torrent:", err)
 			continue
 		}
+		sets.AddUserTorrent(c.GetString(gin.AuthUserKey), tor.Hash().HexString())
 
-		go func() {
-			if !tor.GotInfo() {
-				log.TLogln("error add torrent:", "timeout connection torrent")
-				return
-			}
-
+		go torr.ResolveMeta(tor, func(tor *torr.Torrent) {
 			if tor.Title == "" {
 				tor.Title = tor.Name()
 			}
@@ -66,7 +63,7 @@ func torrentUpload(c *gin.Context) {
 			if save {
 				torr.SaveTorrentToDB(tor)
 			}
-		}()
+		}, nil)
 
 		break
 	}
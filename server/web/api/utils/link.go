@@ -7,11 +7,14 @@ import (
 	"net/http"
 	"net/url"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/anacrolix/torrent"
 	"github.com/anacrolix/torrent/metainfo"
+
+	"server/proxy"
 )
 
 func ParseFile(file multipart.File) (*torrent.TorrentSpec, error) {
@@ -31,6 +34,7 @@ func ParseFile(file multipart.File) (*torrent.TorrentSpec, error) {
 		Trackers:    [][]string{mag.Trackers},
 		DisplayName: info.Name,
 		InfoHash:    minfo.HashInfoBytes(),
+		Webseeds:    minfo.UrlList,
 	}, nil
 }
 
@@ -50,11 +54,40 @@ func ParseLink(link string) (*torrent.TorrentSpec, error) {
 	case "file":
 		return fromFile(urlLink.Path)
 	default:
-		err = fmt.Errorf("unknown scheme:", urlLink, urlLink.Scheme)
+		err = fmt.Errorf("unknown scheme: %v %v", urlLink, urlLink.Scheme)
 	}
 	return nil, err
 }
 
+// ParseSelectOnly returns file indexes of magnet so= param (BEP 53), e.g. so=0,2,4-6
+func ParseSelectOnly(link string) []int {
+	if !strings.HasPrefix(strings.ToLower(link), "magnet:") {
+		return nil
+	}
+	mag, err := metainfo.ParseMagnetURI(link)
+	if err != nil {
+		return nil
+	}
+	var list []int
+	for _, so := range mag.Params["so"] {
+		for _, part := range strings.Split(so, ",") {
+			from, to := part, part
+			if i := strings.Index(part, "-"); i > 0 {
+				from, to = part[:i], part[i+1:]
+			}
+			f, err1 := strconv.Atoi(strings.TrimSpace(from))
+			t, err2 := strconv.Atoi(strings.TrimSpace(to))
+			if err1 != nil || err2 != nil || f < 0 || t < f || t-f > 100000 {
+				continue
+			}
+			for i := f; i <= t; i++ {
+				list = append(list, i)
+			}
+		}
+	}
+	return list
+}
+
 func fromMagnet(link string) (*torrent.TorrentSpec, error) {
 	mag, err := metainfo.ParseMagnetURI(link)
 	if err != nil {
@@ -71,6 +104,8 @@ func fromMagnet(link string) (*torrent.TorrentSpec, error) {
 		Trackers:    trackers,
 		DisplayName: mag.DisplayName,
 		InfoHash:    mag.InfoHash,
+		Webseeds:    mag.Params["ws"],
+		PeerAddrs:   mag.Params["x.pe"],
 	}, nil
 }
 
@@ -80,8 +115,7 @@ func fromHttp(link string) (*torrent.TorrentSpec, error) {
 		return nil, err
 	}
 
-	client := new(http.Client)
-	client.Timeout = time.Duration(time.Second * 60)
+	client := proxy.HTTPClient(proxy.HTTP, time.Second*60)
 	req.Header.Set("User-Agent", "DWL/1.1.1 (Torrent)")
 
 	resp, err := client.Do(req)
@@ -114,6 +148,7 @@ func fromHttp(link string) (*torrent.TorrentSpec, error) {
 		Trackers:    [][]string{mag.Trackers},
 		DisplayName: info.Name,
 		InfoHash:    minfo.HashInfoBytes(),
+		Webseeds:    minfo.UrlList,
 	}, nil
 }
 
@@ -137,5 +172,6 @@ func fromFile(path string) (*torrent.TorrentSpec, error) {
 		Trackers:    [][]string{mag.Trackers},
 		DisplayName: info.Name,
 		InfoHash:    minfo.HashInfoBytes(),
+		Webseeds:    minfo.UrlList,
 	}, nil
 }
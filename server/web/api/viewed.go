@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	sets "server/settings"
+	"server/torr"
 )
 
 /*
@@ -12,9 +13,11 @@ file index starts from 1
 */
 
 // Action: set, rem, list
+// viewed are of authorized user, admin sets user or lists all users
 type viewedReqJS struct {
 	requestI
 	*sets.Viewed
+	All bool `json:"all,omitempty"`
 }
 
 func viewed(c *gin.Context) {
@@ -24,6 +27,13 @@ func viewed(c *gin.Context) {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
+	user := c.GetString(gin.AuthUserKey)
+	if req.Viewed == nil {
+		req.Viewed = &sets.Viewed{}
+	}
+	if !sets.IsAdmin(user) || req.User == "" {
+		req.User = user
+	}
 
 	switch req.Action {
 	case "set":
@@ -42,16 +52,61 @@ func viewed(c *gin.Context) {
 }
 
 func setViewed(req viewedReqJS, c *gin.Context) {
+	if req.Hash == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if !sets.UserHasTorrent(c.GetString(gin.AuthUserKey), req.Hash) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	fillViewed(req.Viewed)
 	sets.SetViewed(req.Viewed)
 	c.Status(200)
 }
 
+// fillViewed completes resume point by length and duration of file, offset
+// and position are found one from another
+func fillViewed(v *sets.Viewed) {
+	tor := torr.GetTorrent(v.Hash)
+	if tor == nil {
+		return
+	}
+	for _, f := range tor.Status().FileStats {
+		if f.Id != v.FileIndex || f.Length <= 0 {
+			continue
+		}
+		duration := 0.0
+		if f.Media != nil {
+			duration = f.Media.Duration
+		}
+		if duration > 0 && v.Offset == 0 && v.Position > 0 {
+			v.Offset = int64(v.Position / duration * float64(f.Length))
+		}
+		if duration > 0 && v.Position == 0 && v.Offset > 0 {
+			v.Position = float64(v.Offset) / float64(f.Length) * duration
+		}
+		if v.Percent == 0 && v.Offset > 0 {
+			v.Percent = float64(v.Offset) * 100 / float64(f.Length)
+		}
+		return
+	}
+}
+
 func remViewed(req viewedReqJS, c *gin.Context) {
+	if req.Hash == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
 	sets.RemViewed(req.Viewed)
 	c.Status(200)
 }
 
 func listViewed(req viewedReqJS, c *gin.Context) {
-	list := sets.ListViewed(req.Hash)
+	if req.All && sets.IsAdmin(c.GetString(gin.AuthUserKey)) {
+		c.JSON(200, sets.ListAllViewed(req.Hash))
+		return
+	}
+	list := sets.ListViewed(req.User, req.Hash)
 	c.JSON(200, list)
 }
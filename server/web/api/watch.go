@@ -0,0 +1,12 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"server/watch"
+)
+
+// http://127.0.0.1:8090/watch
+func watchList(c *gin.Context) {
+	c.JSON(200, watch.List())
+}
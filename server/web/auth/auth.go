@@ -6,9 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
-	"reflect"
-	"strings"
-	"unsafe"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 
@@ -27,6 +25,28 @@ func SetupAuth(engine *gin.Engine) *gin.RouterGroup {
 	return engine.Group("/", BasicAuth(accs))
 }
 
+var (
+	muPairs sync.RWMutex
+	pairs   authPairs
+)
+
+// ReloadAccounts applies accs.db changed by restore of backup, auth is kept
+// if there are no accounts
+func ReloadAccounts() {
+	muPairs.Lock()
+	defer muPairs.Unlock()
+	if pairs == nil {
+		// server started without auth
+		return
+	}
+	accs := getAccounts()
+	if len(accs) == 0 {
+		log.TLogln("No accounts in accs.db, old accounts are used till restart")
+		return
+	}
+	pairs = processAccounts(accs)
+}
+
 func getAccounts() gin.Accounts {
 	buf, err := ioutil.ReadFile(filepath.Join(settings.Path, "accs.db"))
 	if err != nil {
@@ -59,14 +79,18 @@ func (a authPairs) searchCredential(authValue string) (string, bool) {
 }
 
 func BasicAuth(accounts gin.Accounts) gin.HandlerFunc {
-	pairs := processAccounts(accounts)
+	muPairs.Lock()
+	pairs = processAccounts(accounts)
+	muPairs.Unlock()
 	return func(c *gin.Context) {
+		muPairs.RLock()
 		user, found := pairs.searchCredential(c.Request.Header.Get("Authorization"))
+		muPairs.RUnlock()
 		if !found {
-			if strings.HasPrefix(c.FullPath(), "/stream") ||
-				// https://github.com/YouROK/TorrServer/issues/172
-				(strings.HasPrefix(c.FullPath(), "/play") && c.FullPath() != "/playlistall/all.m3u") {
-				c.Set("not_auth", true)
+			// players get signed links in playlists, MSX and DLNA
+			if user, ok := checkSign(c); ok {
+				c.Set("signed", true)
+				c.Set(gin.AuthUserKey, user)
 				return
 			}
 			c.Header("WWW-Authenticate", "Basic realm=Authorization Required")
@@ -91,12 +115,5 @@ func processAccounts(accounts gin.Accounts) authPairs {
 
 func authorizationHeader(user, password string) string {
 	base := user + ":" + password
-	return "Basic " + base64.StdEncoding.EncodeToString(StringToBytes(base))
-}
-
-func StringToBytes(s string) (b []byte) {
-	sh := *(*reflect.StringHeader)(unsafe.Pointer(&s))
-	bh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
-	bh.Data, bh.Len, bh.Cap = sh.Data, sh.Len, sh.Len
-	return b
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(base))
 }
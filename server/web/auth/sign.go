@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"server/settings"
+)
+
+// SignStream returns query args appended to link with "&" which let player
+// without http auth open stream of file till link expires, index is empty
+// for playlist of torrent, without http auth it returns empty string
+func SignStream(user, hash, index string) string {
+	if !settings.HttpAuth {
+		return ""
+	}
+	ttl := 24
+	if settings.BTsets != nil {
+		ttl = settings.BTsets.StreamLinkTTL
+	}
+	exp := strconv.FormatInt(time.Now().Add(time.Duration(ttl)*time.Hour).Unix(), 10)
+	args := url.Values{}
+	args.Set("exp", exp)
+	if user != "" {
+		args.Set("user", user)
+	}
+	args.Set("sig", sign(user, hash, index, exp))
+	return "&" + args.Encode()
+}
+
+func sign(user, hash, index, exp string) string {
+	mac := hmac.New(sha256.New, settings.StreamSecret())
+	mac.Write([]byte(user + "\n" + hash + "\n" + index + "\n" + exp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkSign returns user of signed stream, playlist, play or subtitles request
+func checkSign(c *gin.Context) (string, bool) {
+	var hash, index string
+	switch c.FullPath() {
+	case "/stream", "/stream/*fname":
+		hash, index = c.Query("link"), c.Query("index")
+	case "/play/:hash/:id":
+		hash, index = c.Param("hash"), c.Param("id")
+	case "/subtitles/:hash/:id":
+		hash, index = c.Param("hash"), strings.TrimSuffix(c.Param("id"), ".vtt")
+	case "/playlist", "/playlist/*fname":
+		hash = c.Query("hash")
+	default:
+		return "", false
+	}
+	sig, exp, user := c.Query("sig"), c.Query("exp"), c.Query("user")
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if sig == "" || hash == "" || err != nil || time.Now().Unix() > expires {
+		return "", false
+	}
+	if !hmac.Equal([]byte(sig), []byte(sign(user, hash, index, exp))) {
+		return "", false
+	}
+	return user, true
+}
@@ -0,0 +1,133 @@
+package web
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"server/dlna"
+	"server/settings"
+	"server/torr/utils"
+	utils2 "server/utils"
+	"server/version"
+)
+
+// minDiskFree is free space on cache disk required to be ready
+const minDiskFree = 64 << 20
+
+type healthStatus struct {
+	Status     string                      `json:"status"`
+	Version    string                      `json:"version"`
+	Components map[string]*componentHealth `json:"components"`
+}
+
+type componentHealth struct {
+	OK       bool                   `json:"ok"`
+	Required bool                   `json:"required"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// healthz is liveness check, fails if torrent client or database is down
+func healthz(c *gin.Context) {
+	st := checkHealth()
+	st.Status = "ok"
+	if !st.Components["bt"].OK || !st.Components["db"].OK {
+		st.Status = "fail"
+		c.JSON(503, st)
+		return
+	}
+	c.JSON(200, st)
+}
+
+// readyz is readiness check, fails if any required component is down
+func readyz(c *gin.Context) {
+	st := checkHealth()
+	st.Status = "ok"
+	for _, comp := range st.Components {
+		if comp.Required && !comp.OK {
+			st.Status = "fail"
+			c.JSON(503, st)
+			return
+		}
+	}
+	c.JSON(200, st)
+}
+
+func checkHealth() *healthStatus {
+	st := &healthStatus{
+		Version:    version.Version,
+		Components: make(map[string]*componentHealth),
+	}
+	sets := settings.BTsets
+
+	connected, port, nodes, goodNodes := BTS.ClientState()
+	st.Components["bt"] = &componentHealth{OK: connected, Required: true, Details: map[string]interface{}{"port": port}}
+	if !connected {
+		st.Components["bt"].Error = "torrent client is not connected"
+	}
+
+	dhtOn := sets != nil && !sets.DisableDHT && BTS.DHTEnabled()
+	st.Components["dht"] = &componentHealth{OK: !dhtOn || nodes > 0, Details: map[string]interface{}{
+		"enabled":    dhtOn,
+		"nodes":      nodes,
+		"good_nodes": goodNodes,
+	}}
+
+	open, writable := settings.DBState()
+	st.Components["db"] = &componentHealth{OK: open, Required: true, Details: map[string]interface{}{
+		"open":      open,
+		"writable":  writable,
+		"read_only": settings.ReadOnly,
+	}}
+	if !open {
+		st.Components["db"].Error = "database is not open"
+	}
+
+	dlnaOn := sets != nil && sets.EnableDLNA
+	st.Components["dlna"] = &componentHealth{OK: !dlnaOn || dlna.Running(), Required: dlnaOn, Details: map[string]interface{}{
+		"enabled": dlnaOn,
+		"running": dlna.Running(),
+	}}
+
+	useDisk := sets != nil && sets.UseDisk
+	disk := &componentHealth{OK: true, Required: useDisk, Details: map[string]interface{}{"enabled": useDisk}}
+	if useDisk {
+		disk.Details["path"] = sets.TorrentsSavePath
+		disk.Details["writable"] = false
+		if free, err := utils2.DiskFree(sets.TorrentsSavePath); err == nil {
+			disk.Details["free"] = free
+			if free < minDiskFree {
+				disk.OK = false
+				disk.Error = "low disk space: " + strconv.FormatUint(free, 10) + " bytes"
+			}
+		}
+		if err := checkWritable(sets.TorrentsSavePath); err != nil {
+			disk.OK = false
+			disk.Error = err.Error()
+		} else {
+			disk.Details["writable"] = true
+		}
+	}
+	st.Components["disk"] = disk
+
+	// trackers list is used only if retrackers are added or replaced
+	retrackers := sets != nil && (sets.RetrackersMode == 1 || sets.RetrackersMode == 3)
+	loaded := utils.LoadedTrackers()
+	st.Components["trackers"] = &componentHealth{OK: !retrackers || loaded > 0, Details: map[string]interface{}{
+		"used":   retrackers,
+		"loaded": loaded,
+	}}
+	return st
+}
+
+func checkWritable(dir string) error {
+	ff, err := os.CreateTemp(dir, ".health")
+	if err != nil {
+		return err
+	}
+	name := ff.Name()
+	ff.Close()
+	return os.Remove(name)
+}
@@ -11,6 +11,7 @@ import (
 	"server/torr/state"
 	"server/utils"
 	"server/version"
+	"server/web/auth"
 
 	"github.com/gin-gonic/gin"
 )
@@ -60,6 +61,7 @@ type msxItem struct {
 	Badge       string `json:"badge,omitempty"`
 	Tag         string `json:"tag,omitempty"`
 	Data        gin.H  `json:"data,omitempty"`
+	Properties  gin.H  `json:"properties,omitempty"`
 }
 
 type msxPage struct {
@@ -78,7 +80,7 @@ func msxStart(c *gin.Context) {
 
 // /msx/torrents
 func msxTorrents(c *gin.Context) {
-	torrs := torr.ListTorrent()
+	torrs := torr.ListUserTorrents(c.GetString(gin.AuthUserKey))
 
 	host := utils.GetScheme(c) + "://" + c.Request.Host
 	logo := host + "/apple-touch-icon.png"
@@ -173,7 +175,7 @@ func msxPlaylist(c *gin.Context) {
 
 	host := utils.GetScheme(c) + "://" + c.Request.Host
 	status := tor.Status()
-	viewed := sets.ListViewed(hash)
+	viewed := sets.ListViewed(c.GetString(gin.AuthUserKey), hash)
 	var list []msxItem
 	contentAction := ""
 
@@ -185,7 +187,8 @@ func msxPlaylist(c *gin.Context) {
 			continue
 		}
 		name := filepath.Base(f.Path)
-		uri := host + "/stream/" + url.PathEscape(name) + "?link=" + hash + "&index=" + fmt.Sprint(f.Id) + "&play"
+		uri := host + "/stream/" + url.PathEscape(name) + "?link=" + hash + "&index=" + fmt.Sprint(f.Id) + "&play" +
+			auth.SignStream(c.GetString(gin.AuthUserKey), hash, fmt.Sprint(f.Id))
 		item := msxItem{
 			Label:       name,
 			PlayerLabel: strings.TrimSuffix(name, filepath.Ext(name)),
@@ -211,8 +214,29 @@ func msxPlaylist(c *gin.Context) {
 			contentAction = "panel:request:player:options"
 		}
 
-		if isViewed(viewed, f.Id) {
+		if v := findViewed(viewed, f.Id); v != nil {
 			item.Tag = " "
+			if !v.Watched && v.Position > 0 {
+				// player starts from resume point
+				item.Badge = fmt.Sprintf("%d%%", int(v.Percent))
+				item.Properties = gin.H{"resume:position": int(v.Position)}
+			}
+		}
+		// html5x player loads subtitles from html5x:subtitle:{lang}:{label}
+		for _, s := range f.Subtitles {
+			if item.Properties == nil {
+				item.Properties = gin.H{}
+			}
+			label := s.Label
+			if label == "" {
+				label = filepath.Base(s.Path)
+			}
+			lang := strings.ToLower(strings.ReplaceAll(label, ":", " "))
+			sub := host + "/subtitles/" + hash + "/" + fmt.Sprint(s.Id) + ".vtt"
+			if sign := auth.SignStream(c.GetString(gin.AuthUserKey), hash, fmt.Sprint(s.Id)); sign != "" {
+				sub += "?" + sign[1:]
+			}
+			item.Properties["html5x:subtitle:"+lang+":"+label] = sub
 		}
 		if action == "audio" {
 			item.Icon = "msx-white-soft:music-note"
@@ -227,6 +251,16 @@ func msxPlaylist(c *gin.Context) {
 		return
 	}
 
+	list = append(list, msxItem{
+		Label:  "Magnet",
+		Icon:   "msx-white-soft:link",
+		Action: "link:" + tor.MagnetURI(),
+	}, msxItem{
+		Label:  "Torrent file",
+		Icon:   "msx-white-soft:file-download",
+		Action: "link:" + host + "/torrent/" + hash + ".torrent",
+	})
+
 	res := msxData{
 		Headline: tor.Title,
 		Type:     "list",
@@ -252,11 +286,11 @@ func msxPlaylist(c *gin.Context) {
 	c.JSON(200, res)
 }
 
-func isViewed(viewed []*sets.Viewed, id int) bool {
+func findViewed(viewed []*sets.Viewed, id int) *sets.Viewed {
 	for _, v := range viewed {
 		if v.FileIndex == id {
-			return true
+			return v
 		}
 	}
-	return false
+	return nil
 }
@@ -1,10 +1,10 @@
 package pages
 
 import (
-	"github.com/anacrolix/torrent/metainfo"
+	"html"
+
 	"github.com/gin-gonic/gin"
 
-	"server/settings"
 	"server/torr"
 	"server/web/pages/template"
 )
@@ -21,16 +21,13 @@ func statPage(c *gin.Context) {
 }
 
 func getTorrents(c *gin.Context) {
-	list := settings.ListTorrent()
+	list := torr.ListUserTorrents(c.GetString(gin.AuthUserKey))
 	http := "<div>"
-	for _, db := range list {
-		ts := db.TorrentSpec
-		mi := metainfo.MetaInfo{
-			AnnounceList: ts.Trackers,
-		}
-		//		mag := mi.Magnet(ts.DisplayName, ts.InfoHash)
-		mag := mi.Magnet(&ts.InfoHash, &metainfo.Info{Name: ts.DisplayName})
-		http += "<p><a href='" + mag.String() + "'>magnet:?xt=urn:btih:" + mag.InfoHash.HexString() + "</a></p>"
+	for _, tor := range list {
+		hash := tor.TorrentSpec.InfoHash
+		mag := tor.MagnetURI()
+		http += "<p><a href='" + html.EscapeString(mag) + "'>magnet:?xt=urn:btih:" + hash.HexString() + "</a>"
+		http += " <a href='/torrent/" + hash.HexString() + ".torrent'>.torrent</a></p>"
 	}
 	http += "</div>"
 	c.Data(200, "text/html; charset=utf-8", []byte(http))
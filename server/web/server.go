@@ -1,14 +1,18 @@
 package web
 
 import (
+	"context"
 	"net"
+	"net/http"
 	"sort"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/location"
 	"github.com/gin-gonic/gin"
 
 	"server/dlna"
+	"server/metrics"
 	"server/settings"
 	"server/web/msx"
 
@@ -23,10 +27,11 @@ import (
 
 var (
 	BTS      = torr.NewBTS()
-	waitChan = make(chan error)
+	waitChan = make(chan error, 1)
+	httpSrv  *http.Server
 )
 
-func Start(port string) {
+func Start(host, port string) {
 	log.TLogln("Start TorrServer")
 	ips := getLocalIps()
 	if len(ips) > 0 {
@@ -49,9 +54,11 @@ func Start(port string) {
 	corsCfg.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "X-Requested-With", "Accept", "Authorization"}
 
 	route := gin.New()
-	route.Use(log.WebLogger(), blocker.Blocker(), gin.Recovery(), cors.New(corsCfg), location.Default())
+	route.Use(log.WebLogger(), metrics.WebMetrics(), blocker.Blocker(), gin.Recovery(), cors.New(corsCfg), location.Default())
 
 	route.GET("/echo", echo)
+	route.GET("/healthz", healthz)
+	route.GET("/readyz", readyz)
 
 	routeAuth := auth.SetupAuth(route)
 	if routeAuth != nil {
@@ -66,18 +73,40 @@ func Start(port string) {
 	if settings.BTsets.EnableDLNA {
 		dlna.Start()
 	}
-	log.TLogln("Start web server at port", port)
-	waitChan <- route.Run(":" + port)
+	log.TLogln("Start web server at", net.JoinHostPort(host, port))
+	httpSrv = &http.Server{Addr: net.JoinHostPort(host, port), Handler: route}
+	go func() {
+		if err := httpSrv.ListenAndServe(); err != http.ErrServerClosed {
+			waitChan <- err
+		}
+	}()
 }
 
+// Wait returns error of web server or nil on shutdown request
 func Wait() error {
-	return <-waitChan
+	select {
+	case err := <-waitChan:
+		return err
+	case <-torr.ShutdownRequested():
+		return nil
+	}
 }
 
-func Stop() {
+// Stop stops accepting requests and waits for active streams until ctx is
+// done, then stops DLNA, torrents and torrent client
+func Stop(ctx context.Context) {
+	if httpSrv != nil {
+		if err := httpSrv.Shutdown(ctx); err != nil {
+			log.TLogln("Close active streams:", metrics.Streams.Value())
+			httpSrv.Close()
+			// let handlers see closed connections before torrents close
+			for i := 0; i < 20 && metrics.Streams.Value() > 0; i++ {
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+	}
 	dlna.Stop()
-	BTS.Disconnect()
-	waitChan <- nil
+	torr.CloseAll()
 }
 
 func echo(c *gin.Context) {